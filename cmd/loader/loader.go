@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"nearestPlaces/internal/app"
+	"nearestPlaces/internal/lib/config"
+	"os"
+)
+
+func main() {
+	cfg := config.MustLoad()
+	data := flag.String("data", cfg.DataPath, "path of the data set")
+	format := flag.String("format", cfg.DataFormat, "format of the data set, told from its extension when empty")
+	schema := flag.String("schema", cfg.SchemaPath, "path of the index schema")
+	index := flag.String("index", cfg.Index.Name, "name of the index alias")
+	dryRun := flag.Bool("dry-run", false, "only read and validate the data set")
+	sync := flag.Bool("sync", cfg.Ingest.Mode == config.IngestSync, "update the current generation in place instead of building a new one")
+	flag.Parse()
+
+	cfg.DataPath, cfg.DataFormat, cfg.SchemaPath, cfg.Index.Name = *data, *format, *schema, *index
+	cfg.Ingest.Mode = config.IngestReload
+	if *sync {
+		cfg.Ingest.Mode = config.IngestSync
+	}
+	if err := app.Load(cfg, *dryRun); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"nearestPlaces/internal/app"
+	"nearestPlaces/internal/controller/cli"
+	"nearestPlaces/internal/lib/config"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	cfg := config.MustLoad()
+	index := flag.String("index", cfg.Index.Name, "name of the index alias")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: npctl [-index name] <command> [flags]")
+		flag.PrintDefaults()
+		cli.Usage(flag.CommandLine.Output())
+	}
+	flag.Parse()
+	cfg.Index.Name = *index
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := app.Ctl(ctx, cfg, flag.Args())
+	stop()
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "npctl:", err)
+	if errors.Is(err, cli.ErrUsage) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
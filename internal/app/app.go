@@ -3,79 +3,98 @@ package app
 import (
 	"context"
 	"errors"
-	"fmt"
-	"github.com/elastic/go-elasticsearch/v7"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"log/slog"
 	"nearestPlaces/internal/controller"
 	httpController "nearestPlaces/internal/controller/http"
+	adminController "nearestPlaces/internal/controller/http/v1/admin"
 	"nearestPlaces/internal/controller/http/v1/api"
 	authController "nearestPlaces/internal/controller/http/v1/auth"
 	"nearestPlaces/internal/infrastructure/JSONSchemaReader"
 	"nearestPlaces/internal/infrastructure/csv"
-	"nearestPlaces/internal/infrastructure/repository/elastic"
+	"nearestPlaces/internal/infrastructure/geoip"
 	"nearestPlaces/internal/infrastructure/tokenGenerator/JWTAuthTokenGenerator"
+	"nearestPlaces/internal/infrastructure/uploads"
+	"nearestPlaces/internal/infrastructure/watcher"
 	"nearestPlaces/internal/lib/config"
 	"nearestPlaces/internal/lib/logger/sl"
 	"nearestPlaces/internal/usecase/auth"
+	"nearestPlaces/internal/usecase/geolocation"
 	"nearestPlaces/internal/usecase/restaurants"
 	"nearestPlaces/internal/usecase/store"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 )
 
-const indexName = "places"
-
 func Run(cfg *config.Config) {
 	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	log.Info("logger started successfully")
 
 	// infrastructure
 
-	// db
-	elasticAddr := fmt.Sprintf("http://%s:%s", cfg.Elastic.Host, cfg.Elastic.Port)
-	esConfig := elasticsearch.Config{
-		Addresses: []string{elasticAddr},
-	}
-	es, err := elasticsearch.NewClient(esConfig)
+	// storage
+	storage, err := newStorage(log, cfg)
 	if err != nil {
-		log.Error("failed to create elasticsearch client: ", sl.Err(err))
+		log.Error("failed to create storage: ", sl.Err(err))
 		os.Exit(1)
 	}
-
-	// storage
-	storage := elastic.New(log, es, indexName)
+	log.Info("storage created", slog.String("storage", cfg.Storage))
 
 	mappingReader := JSONSchemaReader.New()
-	csvParser := csv.New()
+	dataImporter := newImporter(cfg)
 
 	ja := jwtauth.New("HS256", []byte(cfg.Token.Secret), nil,
 		jwt.WithAcceptableSkew(cfg.Token.Skew))
 	tokenGenerator := JWTAuthTokenGenerator.New(ja, cfg.Token.TTL)
 
+	var geoIP geolocation.GeoIP
+	if cfg.GeoIP.DBPath != "" {
+		reader, err := geoip.Open(cfg.GeoIP.DBPath)
+		if err != nil {
+			log.Error("failed to open geoip database: ", sl.Err(err))
+			os.Exit(1)
+		}
+		defer reader.Close()
+		geoIP = reader
+		log.Info("geoip database opened", slog.String("path", cfg.GeoIP.DBPath))
+	}
+
 	// use cases
-	restaurantsUseCase := restaurants.New(log, storage)
-	storeUseCase := store.New(log, cfg, mappingReader, csvParser, storage)
-	authUseCase := auth.New(log, tokenGenerator)
-	err = storeUseCase.CreateIndexWithMapping()
-	if err != nil {
-		log.Error("failed to create index: ", sl.Err(err))
+	restaurantsUseCase := restaurants.New(log, cfg, storage)
+	storeUseCase := store.New(log, cfg, mappingReader, dataImporter, storage, rejectionReports(cfg.Ingest.ReportDir),
+		uploads.New(cfg.Ingest.UploadDir))
+	authUseCase := auth.New(log, tokenGenerator, cfg.Token.TTL, cfg.Token.AdminKey)
+	geolocationUseCase := geolocation.New(log, geoIP)
+	if cfg.Ingest.SkipOnStart {
+		log.Info("loading on start skipped")
+	} else if err := load(context.Background(), cfg, storeUseCase); err != nil {
+		log.Error("failed to load data: ", sl.Err(err))
 	}
-	err = storeUseCase.UploadPlaces()
-	if err != nil {
-		log.Error("failed to fill index: ", sl.Err(err))
+
+	// watcher
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.Watch.Enabled {
+		w := watcher.New(log, cfg.Watch.Interval, cfg.Watch.Quiet, cfg.DataPath, csv.MappingPath(cfg.DataPath), cfg.SchemaPath)
+		go w.Run(watchCtx, func(ctx context.Context, paths []string) {
+			if _, err := storeUseCase.SubmitReload(ctx, slices.Contains(paths, cfg.SchemaPath)); err != nil {
+				log.Error("failed to reload changed data set: ", sl.Err(err))
+			}
+		})
 	}
 
 	// controller
-	apiCtrl := api.New(log, restaurantsUseCase)
+	apiCtrl := api.New(log, restaurantsUseCase, geolocationUseCase)
 	authCtrl := authController.New(log, authUseCase)
-	ctrl := controller.New(authCtrl, apiCtrl)
+	adminCtrl := adminController.New(log, storeUseCase, cfg.Ingest.MaxUploadSize, cfg.Ingest.UploadTimeout)
+	ctrl := controller.New(authCtrl, apiCtrl, adminCtrl)
 
 	// router
-	router := httpController.NewRouter(log, ctrl, ja)
+	router := httpController.NewRouter(log, cfg, ctrl, ja)
 
 	// server
 	addr := cfg.Server.Host + ":" + cfg.Server.Port
@@ -106,5 +125,13 @@ func Run(cfg *config.Config) {
 		log.Error("error while shutting down server", sl.Err(err))
 	}
 
+	// A job cut short drops the generation it was filling and its upload before the process exits.
+	stopWatching()
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelJobs()
+	if err := storeUseCase.Shutdown(jobsCtx); err != nil {
+		log.Error("ingestion jobs did not stop in time", sl.Err(err))
+	}
+
 	log.Info("shut down successfully")
 }
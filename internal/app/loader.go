@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"nearestPlaces/internal/infrastructure/JSONSchemaReader"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/lib/logger/sl"
+	"nearestPlaces/internal/usecase/store"
+	"os"
+)
+
+// Load loads the configured data set once, in the configured ingest mode, as the server does on start.
+// With dryRun the data set is only read and validated, and the storage is left untouched.
+// It is run by cmd/loader, so that servers sharing an index need not each reload it,
+// and only loads elastic storage; a dry run can be made against any.
+func Load(cfg *config.Config, dryRun bool) error {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	log.Info("logger started successfully")
+
+	// Memory storage lives in the process, so whatever the loader put there would be gone when it exits.
+	if cfg.Storage != config.StorageElastic && !dryRun {
+		err := fmt.Errorf("the loader fills elastic storage only, not %q; memory storage is loaded by the server on start", cfg.Storage)
+		log.Error("cannot load data: ", sl.Err(err))
+		return err
+	}
+
+	storage, err := newStorage(log, cfg)
+	if err != nil {
+		log.Error("failed to create storage: ", sl.Err(err))
+		return err
+	}
+	log.Info("storage created", slog.String("storage", cfg.Storage))
+
+	storeUseCase := store.New(log, cfg, JSONSchemaReader.New(), newImporter(cfg), storage, rejectionReports(cfg.Ingest.ReportDir), nil)
+	if dryRun {
+		err = storeUseCase.Check(context.Background())
+	} else {
+		err = load(context.Background(), cfg, storeUseCase)
+	}
+	if err != nil {
+		log.Error("failed to load data: ", sl.Err(err), slog.Bool("dry_run", dryRun))
+		return err
+	}
+	if dryRun {
+		log.Info("data set is valid")
+	} else {
+		log.Info("data loaded")
+	}
+	return nil
+}
+
+// load builds a new generation from the data set and makes it current,
+// or in sync mode brings the current generation in line with the data set.
+func load(ctx context.Context, cfg *config.Config, uc *store.UseCase) error {
+	if cfg.Ingest.Mode == config.IngestSync {
+		_, err := uc.Sync(ctx)
+		return err
+	}
+	index, err := uc.CreateIndexWithMapping(ctx)
+	if err != nil {
+		return err
+	}
+	return uc.UploadPlaces(ctx, index)
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+	"github.com/go-chi/jwtauth/v5"
+	"log/slog"
+	"nearestPlaces/internal/controller/cli"
+	"nearestPlaces/internal/infrastructure/JSONSchemaReader"
+	"nearestPlaces/internal/infrastructure/tokenGenerator/JWTAuthTokenGenerator"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/usecase/store"
+	"os"
+)
+
+// Ctl runs a command of cmd/npctl against the Elasticsearch cluster of the config.
+// Only warnings and errors are logged, to standard error, so that the output of commands can be piped.
+func Ctl(ctx context.Context, cfg *config.Config, args []string) error {
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	storage, err := newElastic(log, cfg)
+	if err != nil {
+		return err
+	}
+	ja := jwtauth.New("HS256", []byte(cfg.Token.Secret), nil)
+	tokenGenerator := JWTAuthTokenGenerator.New(ja, cfg.Token.TTL)
+	schemaReader := JSONSchemaReader.New()
+	storeUseCase := store.New(log, cfg, schemaReader, newImporter(cfg), storage, rejectionReports(cfg.Ingest.ReportDir), nil)
+	return cli.New(os.Stdout, cfg, storage, storeUseCase, schemaReader, tokenGenerator).Run(ctx, args)
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7"
+	"log/slog"
+	"nearestPlaces/internal/infrastructure/csv"
+	"nearestPlaces/internal/infrastructure/geojson"
+	"nearestPlaces/internal/infrastructure/importer"
+	"nearestPlaces/internal/infrastructure/ndjson"
+	"nearestPlaces/internal/infrastructure/osm"
+	"nearestPlaces/internal/infrastructure/rejections"
+	"nearestPlaces/internal/infrastructure/repository/elastic"
+	"nearestPlaces/internal/infrastructure/repository/memory"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/usecase/restaurants"
+	"nearestPlaces/internal/usecase/store"
+	"path/filepath"
+)
+
+type storage interface {
+	restaurants.Store
+	store.Storage
+}
+
+func newStorage(log *slog.Logger, cfg *config.Config) (storage, error) {
+	switch cfg.Storage {
+	case config.StorageElastic:
+		return newElastic(log, cfg)
+	case config.StorageMemory:
+		return memory.New(log, cfg.Index.Name), nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Storage)
+	}
+}
+
+func newElastic(log *slog.Logger, cfg *config.Config) (*elastic.Storage, error) {
+	elasticAddr := fmt.Sprintf("http://%s:%s", cfg.Elastic.Host, cfg.Elastic.Port)
+	esConfig := elasticsearch.Config{
+		Addresses: []string{elasticAddr},
+	}
+	es, err := elasticsearch.NewClient(esConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch client: %w", err)
+	}
+	return elastic.New(log, es, cfg.Index.Name, elastic.BulkOptions{
+		Workers:       cfg.Ingest.IndexWorkers,
+		FlushBytes:    cfg.Ingest.FlushBytes,
+		FlushInterval: cfg.Ingest.FlushInterval,
+	}), nil
+}
+
+// newImporter hands each data set to the importer of its format.
+func newImporter(cfg *config.Config) *importer.Registry {
+	return importer.New(map[string]importer.Importer{
+		importer.FormatCSV:     csv.New(cfg.Ingest.ParseWorkers, cfg.Ingest.Buffer),
+		importer.FormatGeoJSON: geojson.New(cfg.Ingest.Buffer),
+		importer.FormatNDJSON:  ndjson.New(cfg.Ingest.Buffer),
+		importer.FormatOSM:     osm.New(cfg.Ingest.Buffer),
+	})
+}
+
+// rejectionReports writes the report of each load into dir, named after the generation it fills.
+func rejectionReports(dir string) store.ReportOpener {
+	return func(index string) (store.RejectionReport, error) {
+		report, err := rejections.Create(filepath.Join(dir, index+"-rejected.ndjson"))
+		if err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+}
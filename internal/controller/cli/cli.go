@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/usecase/auth"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUsage is returned for a command line that names no known command or has invalid flags.
+var ErrUsage = errors.New("invalid usage")
+
+type Storage interface {
+	CreateIndex(ctx context.Context, mappings []byte) (string, error)
+	SwitchAlias(ctx context.Context, index string) error
+	DeleteIndex(ctx context.Context, index string) error
+	Inspect(ctx context.Context, name string) ([]*entity.IndexInfo, error)
+	Aliases(ctx context.Context) ([]*entity.Alias, error)
+	GetClosest(ctx context.Context, lat, lon float64, limit int, radius float64) ([]*entity.NearbyPlace, error)
+	Export(ctx context.Context, name string, visit func(place *entity.Restaurant) error) error
+}
+
+// Generations lists the generations of the index and rolls the alias back to one of them.
+type Generations interface {
+	Generations(ctx context.Context) ([]*entity.Generation, error)
+	Rollback(ctx context.Context, to string) (string, error)
+}
+
+type SchemaReader interface {
+	ReadMappings(filename string) ([]byte, error)
+}
+
+type TokenGenerator interface {
+	GenerateWithClaims(ttl time.Duration, claims map[string]interface{}) (string, error)
+}
+
+// CLI runs the commands of npctl, the tool operators manage the index and mint tokens with.
+// Commands print JSON, except for export, which prints a place per line, and token.
+type CLI struct {
+	out         io.Writer
+	cfg         *config.Config
+	storage     Storage
+	generations Generations
+	schema      SchemaReader
+	tokens      TokenGenerator
+}
+
+func New(out io.Writer, cfg *config.Config, storage Storage, generations Generations, schema SchemaReader, tokens TokenGenerator) *CLI {
+	return &CLI{
+		out:         out,
+		cfg:         cfg,
+		storage:     storage,
+		generations: generations,
+		schema:      schema,
+		tokens:      tokens,
+	}
+}
+
+type command struct {
+	usage string
+	run   func(c *CLI, ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"create":      {"create [-schema path] [-switch]  create a new generation of the index, making it current with -switch", (*CLI).create},
+	"drop":        {"drop [-force] <index>  delete an index; the current generation only with -force", (*CLI).drop},
+	"inspect":     {"inspect [index]  show the document count, size and mappings of an index, the alias by default", (*CLI).inspect},
+	"aliases":     {"aliases  list the aliases and the indices they point to", (*CLI).aliases},
+	"generations": {"generations  list the generations of the index, newest first", (*CLI).listGenerations},
+	"rollback":    {"rollback [-to index]  make an older generation current, the one before the current by default", (*CLI).rollback},
+	"nearest":     {"nearest -lat <lat> -lon <lon> [-limit n] [-radius m]  run a nearest places query", (*CLI).nearest},
+	"export":      {"export [-o file] [index]  write the places of an index, the alias by default, as NDJSON", (*CLI).export},
+	"token":       {"token [-ttl d] [-admin] [-claim key=value]...  mint a token signed with the configured secret", (*CLI).token},
+}
+
+// Usage lists the commands.
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "commands:")
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+commands[name].usage)
+	}
+}
+
+// Run runs the command named by the first argument with the rest of the arguments.
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no command given", ErrUsage)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, args[0])
+	}
+	return cmd.run(c, ctx, args[1:])
+}
+
+// flags creates the flag set of a command; parse errors are returned as usage errors.
+func flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrUsage, fs.Name(), err)
+	}
+	return nil
+}
+
+func (c *CLI) print(v interface{}) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (c *CLI) create(ctx context.Context, args []string) error {
+	fs := flags("create")
+	schema := fs.String("schema", c.cfg.SchemaPath, "path of the index schema")
+	switchAlias := fs.Bool("switch", false, "make the new generation current")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	mappings, err := c.schema.ReadMappings(*schema)
+	if err != nil {
+		return err
+	}
+	index, err := c.storage.CreateIndex(ctx, mappings)
+	if err != nil {
+		return err
+	}
+	if *switchAlias {
+		if err := c.storage.SwitchAlias(ctx, index); err != nil {
+			return err
+		}
+	}
+	return c.print(map[string]interface{}{"index": index, "current": *switchAlias})
+}
+
+func (c *CLI) drop(ctx context.Context, args []string) error {
+	fs := flags("drop")
+	force := fs.Bool("force", false, "drop the index even if an alias points to it")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: drop takes a single index", ErrUsage)
+	}
+	index := fs.Arg(0)
+	if !*force {
+		aliases, err := c.storage.Aliases(ctx)
+		if err != nil {
+			return err
+		}
+		for _, a := range aliases {
+			for _, i := range a.Indices {
+				if i == index {
+					return fmt.Errorf("%w: alias %s points to %s; drop it with -force", entity.ErrInvalidArgument, a.Name, index)
+				}
+			}
+		}
+	}
+	if err := c.storage.DeleteIndex(ctx, index); err != nil {
+		return err
+	}
+	return c.print(map[string]string{"dropped": index})
+}
+
+func (c *CLI) inspect(ctx context.Context, args []string) error {
+	fs := flags("inspect")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	name := c.cfg.Index.Name
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	infos, err := c.storage.Inspect(ctx, name)
+	if err != nil {
+		return err
+	}
+	return c.print(infos)
+}
+
+func (c *CLI) aliases(ctx context.Context, args []string) error {
+	fs := flags("aliases")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	aliases, err := c.storage.Aliases(ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(aliases)
+}
+
+func (c *CLI) listGenerations(ctx context.Context, args []string) error {
+	fs := flags("generations")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	generations, err := c.generations.Generations(ctx)
+	if err != nil {
+		return err
+	}
+	return c.print(generations)
+}
+
+func (c *CLI) rollback(ctx context.Context, args []string) error {
+	fs := flags("rollback")
+	to := fs.String("to", "", "generation to make current")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	current, err := c.generations.Rollback(ctx, *to)
+	if err != nil {
+		return err
+	}
+	return c.print(map[string]string{"current": current})
+}
+
+func (c *CLI) nearest(ctx context.Context, args []string) error {
+	fs := flags("nearest")
+	lat := fs.Float64("lat", 0, "latitude of the point")
+	lon := fs.Float64("lon", 0, "longitude of the point")
+	limit := fs.Int("limit", c.cfg.Recommend.DefaultLimit, "number of places")
+	radius := fs.Float64("radius", 0, "largest distance in metres, unbounded when 0")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *lat < -90 || *lat > 90 || *lon < -180 || *lon > 180 {
+		return fmt.Errorf("%w: point %g,%g is off the globe", ErrUsage, *lat, *lon)
+	}
+	places, err := c.storage.GetClosest(ctx, *lat, *lon, max(*limit, 1), *radius)
+	if err != nil {
+		return err
+	}
+	return c.print(places)
+}
+
+func (c *CLI) export(ctx context.Context, args []string) (err error) {
+	fs := flags("export")
+	output := fs.String("o", "", "file to write, standard output when empty")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	name := c.cfg.Index.Name
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+
+	out := c.out
+	if *output != "" {
+		var file *os.File
+		file, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		// A failed export leaves no partial file behind to be mistaken for a complete one.
+		defer func() {
+			if closeErr := file.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to write %s: %w", *output, closeErr)
+			}
+			if err != nil {
+				os.Remove(*output)
+			}
+		}()
+		out = file
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	err = c.storage.Export(ctx, name, func(place *entity.Restaurant) error {
+		return enc.Encode(place)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (c *CLI) token(ctx context.Context, args []string) error {
+	fs := flags("token")
+	ttl := fs.Duration("ttl", c.cfg.Token.TTL, "how long the token lives")
+	isAdmin := fs.Bool("admin", false, "grant access to the admin API")
+	extra := claims{}
+	fs.Var(extra, "claim", "a claim as key=value; values that are JSON, such as true or 42, keep their type")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *ttl <= 0 {
+		return fmt.Errorf("%w: ttl must be positive", ErrUsage)
+	}
+	if *isAdmin {
+		extra[auth.AdminClaim] = true
+	}
+	token, err := c.tokens.GenerateWithClaims(*ttl, extra)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.out, token)
+	return err
+}
+
+// claims collects the -claim flags of the token command.
+type claims map[string]interface{}
+
+// reservedClaims are the registered claims every token gets from the generator; -ttl sets the expiry.
+var reservedClaims = map[string]bool{"exp": true, "iat": true, "iss": true, "aud": true, "jti": true}
+
+func (cl claims) String() string {
+	return fmt.Sprint(map[string]interface{}(cl))
+}
+
+func (cl claims) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return errors.New("claim must be key=value")
+	}
+	if reservedClaims[key] {
+		return fmt.Errorf("claim %s is set by the token generator", key)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+	cl[key] = v
+	return nil
+}
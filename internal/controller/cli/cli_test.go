@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type storageStub struct {
+	Storage
+	aliases []*entity.Alias
+	places  []*entity.Restaurant
+	dropped []string
+	// exportErr fails Export after the first place is written.
+	exportErr error
+}
+
+func (s *storageStub) Aliases(ctx context.Context) ([]*entity.Alias, error) {
+	return s.aliases, nil
+}
+
+func (s *storageStub) DeleteIndex(ctx context.Context, index string) error {
+	s.dropped = append(s.dropped, index)
+	return nil
+}
+
+func (s *storageStub) Export(ctx context.Context, name string, visit func(place *entity.Restaurant) error) error {
+	for _, p := range s.places {
+		if err := visit(p); err != nil {
+			return err
+		}
+		if s.exportErr != nil {
+			return s.exportErr
+		}
+	}
+	return nil
+}
+
+type generationsStub struct{}
+
+func (g generationsStub) Generations(ctx context.Context) ([]*entity.Generation, error) {
+	return nil, nil
+}
+
+func (g generationsStub) Rollback(ctx context.Context, to string) (string, error) {
+	if to == "places-2" {
+		return "", entity.ErrNotFound
+	}
+	if to == "" {
+		to = "places-0"
+	}
+	return to, nil
+}
+
+type tokensStub struct {
+	ttl    time.Duration
+	claims map[string]interface{}
+}
+
+func (t *tokensStub) GenerateWithClaims(ttl time.Duration, claims map[string]interface{}) (string, error) {
+	t.ttl, t.claims = ttl, claims
+	return "token", nil
+}
+
+func TestCLI_Run(t *testing.T) {
+	cfg := &config.Config{Index: config.Index{Name: "places"}, Token: config.Token{TTL: 10 * time.Minute}}
+	tests := []struct {
+		name        string
+		args        []string
+		wantErr     error
+		wantOut     string
+		wantDropped []string
+	}{
+		{name: "no command", args: nil, wantErr: ErrUsage},
+		{name: "unknown command", args: []string{"reindex"}, wantErr: ErrUsage},
+		{name: "drop current", args: []string{"drop", "places-1"}, wantErr: entity.ErrInvalidArgument},
+		{name: "drop current with force", args: []string{"drop", "-force", "places-1"}, wantOut: "{\n  \"dropped\": \"places-1\"\n}\n", wantDropped: []string{"places-1"}},
+		{name: "drop old", args: []string{"drop", "places-0"}, wantOut: "{\n  \"dropped\": \"places-0\"\n}\n", wantDropped: []string{"places-0"}},
+		{name: "drop without index", args: []string{"drop"}, wantErr: ErrUsage},
+		{name: "export", args: []string{"export"}, wantOut: `{"id":"1","name":"A","address":"","phone":"","location":{"lon":37.6,"lat":55.7}}` + "\n"},
+		{name: "nearest off the globe", args: []string{"nearest", "-lat", "91"}, wantErr: ErrUsage},
+		{name: "rollback", args: []string{"rollback"}, wantOut: "{\n  \"current\": \"places-0\"\n}\n"},
+		{name: "rollback to", args: []string{"rollback", "-to", "places-1"}, wantOut: "{\n  \"current\": \"places-1\"\n}\n"},
+		{name: "rollback to unknown", args: []string{"rollback", "-to", "places-2"}, wantErr: entity.ErrNotFound},
+		{name: "token", args: []string{"token"}, wantOut: "token\n"},
+		{name: "token with reserved claim", args: []string{"token", "-claim", "exp=0"}, wantErr: ErrUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &storageStub{
+				aliases: []*entity.Alias{{Name: "places", Indices: []string{"places-1"}}},
+				places:  []*entity.Restaurant{{ID: "1", Name: "A"}},
+			}
+			storage.places[0].Location.Lon, storage.places[0].Location.Lat = 37.6, 55.7
+			var out bytes.Buffer
+			err := New(&out, cfg, storage, generationsStub{}, nil, &tokensStub{}).Run(context.Background(), tt.args)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("Run() output = %q, want %q", out.String(), tt.wantOut)
+			}
+			if !reflect.DeepEqual(storage.dropped, tt.wantDropped) {
+				t.Errorf("Run() dropped = %v, want %v", storage.dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestCLI_token(t *testing.T) {
+	cfg := &config.Config{Token: config.Token{TTL: 10 * time.Minute}}
+	tokens := &tokensStub{}
+	args := []string{"token", "-ttl", "1h", "-admin", "-claim", "sub=ops", "-claim", "level=3", "-claim", "note=a=b"}
+	if err := New(&bytes.Buffer{}, cfg, nil, nil, nil, tokens).Run(context.Background(), args); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := map[string]interface{}{"admin": true, "sub": "ops", "level": float64(3), "note": "a=b"}
+	if tokens.ttl != time.Hour || !reflect.DeepEqual(tokens.claims, want) {
+		t.Errorf("Run() minted a token for %v with %v, want %v with %v", tokens.ttl, tokens.claims, time.Hour, want)
+	}
+}
+
+func TestCLI_exportFile(t *testing.T) {
+	cfg := &config.Config{Index: config.Index{Name: "places"}}
+	failed := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		exportErr error
+		wantFile  bool
+	}{
+		{name: "written", wantFile: true},
+		{name: "failed export is removed", exportErr: failed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &storageStub{places: []*entity.Restaurant{{ID: "1", Name: "A"}}, exportErr: tt.exportErr}
+			path := filepath.Join(t.TempDir(), "places.ndjson")
+			err := New(&bytes.Buffer{}, cfg, storage, nil, nil, nil).Run(context.Background(), []string{"export", "-o", path})
+			if !errors.Is(err, tt.exportErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.exportErr)
+			}
+			data, err := os.ReadFile(path)
+			if !tt.wantFile {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("Run() left %s behind: %q", path, data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.HasPrefix(data, []byte(`{"id":"1"`)) {
+				t.Errorf("Run() wrote %q", data)
+			}
+		})
+	}
+}
@@ -1,18 +1,21 @@
 package controller
 
 import (
+	adminController "nearestPlaces/internal/controller/http/v1/admin"
 	apiController "nearestPlaces/internal/controller/http/v1/api"
 	authController "nearestPlaces/internal/controller/http/v1/auth"
 )
 
 type Controllers struct {
-	Auth authController.Auther
-	Api  apiController.APIer
+	Auth  authController.Auther
+	Api   apiController.APIer
+	Admin adminController.Adminer
 }
 
-func New(auth authController.Auther, api apiController.APIer) *Controllers {
+func New(auth authController.Auther, api apiController.APIer, admin adminController.Adminer) *Controllers {
 	return &Controllers{
-		Auth: auth,
-		Api:  api,
+		Auth:  auth,
+		Api:   api,
+		Admin: admin,
 	}
 }
@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"log/slog"
+	"nearestPlaces/internal/lib/api/response"
+	"nearestPlaces/internal/usecase/auth"
+	"net/http"
+)
+
+// ClaimKey is the JWT claim that grants access to admin routes.
+const ClaimKey = auth.AdminClaim
+
+// New lets through only requests whose verified token carries a true admin claim.
+// It must be mounted after jwtauth.Verifier and token.New.
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	log = log.With(
+		slog.String("component", "middleware/admin"),
+	)
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil || claims[ClaimKey] != true {
+				log.Warn("admin claim is missing",
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+				response.Render(w, r, response.ErrForbidden())
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,34 @@
+package budget
+
+import (
+	"context"
+	"log/slog"
+	"nearestPlaces/internal/lib/config"
+	"net/http"
+)
+
+// New puts a deadline on the context of every request: the budget configured for its path,
+// or the default one. Storage queries running past the deadline are abandoned.
+func New(log *slog.Logger, cfg config.Budgets) func(next http.Handler) http.Handler {
+	log = log.With(
+		slog.String("component", "middleware/budget"),
+	)
+	log.Info("budget middleware enabled", slog.String("default", cfg.Default.String()))
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			budget, ok := cfg.Routes[r.URL.Path]
+			if !ok {
+				budget = cfg.Default
+			}
+			if budget <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), budget)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,65 @@
+package clientip
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// New stores the address of the client in the request context. It is the address of the peer
+// unless the peer is one of the trusted proxies; then X-Forwarded-For is walked from the right,
+// past the trusted proxies, to the first address a trusted proxy has vouched for.
+func New(trusted []netip.Prefix) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := Resolve(remoteAddr(r.RemoteAddr), r.Header.Values("X-Forwarded-For"), trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, ip)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// FromContext returns the client address New has stored, or the zero address without it.
+func FromContext(ctx context.Context) netip.Addr {
+	ip, _ := ctx.Value(ctxKey{}).(netip.Addr)
+	return ip
+}
+
+// Resolve picks the client address from the peer address and the X-Forwarded-For headers.
+func Resolve(remote netip.Addr, forwardedFor []string, trusted []netip.Prefix) netip.Addr {
+	var hops []string
+	for _, h := range forwardedFor {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	ip := remote
+	for i := len(hops) - 1; i >= 0 && isTrusted(ip, trusted); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Whatever is left of a malformed entry was not written by a trusted proxy.
+			break
+		}
+		ip = hop.Unmap()
+	}
+	return ip
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, p := range trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteAddr(addr string) netip.Addr {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return ap.Addr().Unmap()
+	}
+	ip, _ := netip.ParseAddr(addr)
+	return ip.Unmap()
+}
@@ -0,0 +1,36 @@
+package clientip
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+	tests := []struct {
+		name         string
+		remote       string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct client", remote: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted peer is not believed", remote: "203.0.113.7", forwardedFor: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remote: "10.0.0.2", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "chain of proxies", remote: "10.0.0.2", forwardedFor: []string{"198.51.100.1, 10.0.0.3"}, want: "198.51.100.1"},
+		{name: "spoofed entry on the left", remote: "10.0.0.2", forwardedFor: []string{"192.0.2.66, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "several headers", remote: "::1", forwardedFor: []string{"198.51.100.1", "10.0.0.3"}, want: "198.51.100.1"},
+		{name: "malformed entry", remote: "10.0.0.2", forwardedFor: []string{"198.51.100.1, unknown"}, want: "10.0.0.2"},
+		{name: "only proxies", remote: "10.0.0.2", forwardedFor: []string{"10.0.0.4, 10.0.0.3"}, want: "10.0.0.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resolve(netip.MustParseAddr(tt.remote), tt.forwardedFor, trusted)
+			if got != netip.MustParseAddr(tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
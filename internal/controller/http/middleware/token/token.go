@@ -0,0 +1,56 @@
+package token
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"log/slog"
+	"nearestPlaces/internal/lib/api/response"
+	"nearestPlaces/internal/lib/logger/sl"
+	"net/http"
+)
+
+// New lets through only requests with a valid token. It replaces jwtauth.Authenticator,
+// so that rejected requests get a problem response like any other failure.
+// It must be mounted after jwtauth.Verifier.
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	log = log.With(
+		slog.String("component", "middleware/token"),
+	)
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil {
+				log := log.With(
+					slog.String("path", r.URL.Path),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+				detail := "A bearer token is required."
+				if err != nil && !errors.Is(err, jwtauth.ErrNoTokenFound) {
+					log = log.With(sl.Err(err))
+					detail = rejection(err)
+				}
+				log.Warn("request is not authenticated")
+				response.Render(w, r, response.ErrUnauthorized(detail))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// rejection tells the client why its token was refused without passing on the verifier's error,
+// which only goes to the log.
+func rejection(err error) string {
+	switch {
+	case errors.Is(err, jwtauth.ErrExpired):
+		return "The token has expired."
+	case errors.Is(err, jwtauth.ErrNBFInvalid), errors.Is(err, jwtauth.ErrIATInvalid):
+		return "The token is not valid yet."
+	default:
+		// A bad signature, a wrong algorithm and a malformed token are all reported alike.
+		return "The token is invalid."
+	}
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"encoding/json"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ja := jwtauth.New("HS256", []byte("secret"), nil, jwt.WithAcceptableSkew(time.Second))
+	forged := jwtauth.New("HS256", []byte("other secret"), nil)
+	now := time.Now()
+	encode := func(ja *jwtauth.JWTAuth, claims map[string]interface{}) string {
+		_, s, err := ja.Encode(claims)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantDetail string
+	}{
+		{"valid", encode(ja, map[string]interface{}{"exp": now.Add(time.Hour).Unix()}), http.StatusOK, ""},
+		{"missing", "", http.StatusUnauthorized, "A bearer token is required."},
+		{"expired", encode(ja, map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}), http.StatusUnauthorized, "The token has expired."},
+		{"not yet valid", encode(ja, map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}), http.StatusUnauthorized, "The token is not valid yet."},
+		{"issued in the future", encode(ja, map[string]interface{}{"iat": now.Add(time.Hour).Unix()}), http.StatusUnauthorized, "The token is not valid yet."},
+		{"bad signature", encode(forged, map[string]interface{}{"exp": now.Add(time.Hour).Unix()}), http.StatusUnauthorized, "The token is invalid."},
+		{"malformed", "not.a.token", http.StatusUnauthorized, "The token is invalid."},
+	}
+	handler := jwtauth.Verifier(ja)(New(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/recommend", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantDetail == "" {
+				return
+			}
+			var problem struct {
+				Detail string `json:"detail"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatalf("failed to decode the problem: %v", err)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
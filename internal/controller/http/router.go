@@ -6,25 +6,80 @@ import (
 	"github.com/go-chi/jwtauth/v5"
 	"log/slog"
 	"nearestPlaces/internal/controller"
+	"nearestPlaces/internal/controller/http/middleware/admin"
+	"nearestPlaces/internal/controller/http/middleware/budget"
+	"nearestPlaces/internal/controller/http/middleware/clientip"
 	"nearestPlaces/internal/controller/http/middleware/logger"
+	"nearestPlaces/internal/controller/http/middleware/token"
+	"nearestPlaces/internal/lib/api/response"
+	"nearestPlaces/internal/lib/config"
 	"net/http"
+	"strings"
+	"time"
 )
 
-func NewRouter(log *slog.Logger, ctrl *controller.Controllers, ja *jwtauth.JWTAuth) http.Handler {
+// uploadPath is where data sets are uploaded to, which takes longer than a query.
+const uploadPath = "/api/admin/datasets"
+
+func NewRouter(log *slog.Logger, cfg *config.Config, ctrl *controller.Controllers, ja *jwtauth.JWTAuth) http.Handler {
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
 	router.Use(middleware.Recoverer)
 	router.Use(logger.New(log))
+	router.Use(budget.New(log, withUploadBudget(cfg)))
+	router.Use(clientip.New(cfg.GeoIP.TrustedProxies))
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		renderProblem(w, r, response.ErrNotFound())
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		renderProblem(w, r, response.ErrMethodNotAllowed())
+	})
 	router.Get("/", ctrl.Api.Paginate)
 	router.Route("/api", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
 			r.Use(jwtauth.Verifier(ja))
-			r.Use(jwtauth.Authenticator(ja))
+			r.Use(token.New(log))
 			r.Get("/recommend", ctrl.Api.Recommend)
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(admin.New(log))
+				r.Get("/generations", ctrl.Admin.Generations)
+				r.Post("/generations/rollback", ctrl.Admin.Rollback)
+				r.Post("/datasets", ctrl.Admin.UploadDataset)
+				r.Get("/jobs/{id}", ctrl.Admin.Job)
+			})
 		})
 
 		r.Get("/places", ctrl.Api.Places)
+		r.Get("/places/within", ctrl.Api.Within)
+		r.Post("/places/within", ctrl.Api.Within)
+		r.Get("/places/clusters", ctrl.Api.Clusters)
+		r.Get("/search", ctrl.Api.Search)
+		r.Get("/suggest", ctrl.Api.Suggest)
 		r.Get("/get_token", ctrl.Auth.GetToken)
 	})
 	return router
 }
+
+// renderProblem answers API requests with problem details and browser requests with an error page.
+func renderProblem(w http.ResponseWriter, r *http.Request, p *response.Problem) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		response.Render(w, r, p)
+		return
+	}
+	response.RenderHTML(w, r, p)
+}
+
+// withUploadBudget gives uploads ingest.upload_timeout as their budget, unless the budgets name one for them.
+func withUploadBudget(cfg *config.Config) config.Budgets {
+	budgets := cfg.Budgets
+	if _, ok := budgets.Routes[uploadPath]; ok {
+		return budgets
+	}
+	budgets.Routes = make(map[string]time.Duration, len(cfg.Budgets.Routes)+1)
+	for path, budget := range cfg.Budgets.Routes {
+		budgets.Routes[path] = budget
+	}
+	budgets.Routes[uploadPath] = cfg.Ingest.UploadTimeout
+	return budgets
+}
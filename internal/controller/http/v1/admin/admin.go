@@ -0,0 +1,209 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/api/response"
+	"nearestPlaces/internal/lib/logger/sl"
+	"nearestPlaces/internal/usecase"
+	"net/http"
+	"time"
+)
+
+type Adminer interface {
+	Generations(w http.ResponseWriter, r *http.Request)
+	Rollback(w http.ResponseWriter, r *http.Request)
+	UploadDataset(w http.ResponseWriter, r *http.Request)
+	Job(w http.ResponseWriter, r *http.Request)
+}
+
+// uploadMemory is how much of an upload is held in memory; the rest is spilled to temporary files.
+const uploadMemory = 32 << 20
+
+type Controller struct {
+	log *slog.Logger
+	uc  usecase.Storer
+	// maxUpload caps the size in bytes of an upload request.
+	maxUpload int64
+	// uploadTimeout is how long an upload request may take, in place of the server timeouts.
+	uploadTimeout time.Duration
+}
+
+func New(log *slog.Logger, uc usecase.Storer, maxUpload int64, uploadTimeout time.Duration) *Controller {
+	return &Controller{
+		log:           log,
+		uc:            uc,
+		maxUpload:     maxUpload,
+		uploadTimeout: uploadTimeout,
+	}
+}
+
+type GenerationsResponse struct {
+	Generations []*entity.Generation `json:"generations"`
+}
+
+type RollbackResponse struct {
+	Current string `json:"current"`
+}
+
+func (c *Controller) Generations(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.admin.Generations"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	log.Info("request received")
+
+	generations, err := c.uc.Generations(r.Context())
+	if err != nil {
+		log.Error("failed to list generations", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(GenerationsResponse{Generations: generations})
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+// Rollback makes the generation from the 'to' query parameter current.
+// Without it the previous generation is restored.
+func (c *Controller) Rollback(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.admin.Rollback"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	to := r.URL.Query().Get("to")
+	log.Info("request received", slog.String("to", to))
+
+	current, err := c.uc.Rollback(r.Context(), to)
+	if errors.Is(err, usecase.ErrGenerationNotFound) {
+		log.Error("generation not found", sl.Err(err))
+		resp := fmt.Sprintf("No generation to roll back to: '%s'.", to)
+		response.Render(w, r, response.ErrNotFoundText(resp))
+		return
+	} else if err != nil {
+		log.Error("failed to roll back", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+	log.Info("rolled back", slog.String("current", current))
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(RollbackResponse{Current: current})
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+// UploadDataset accepts a data set sent as multipart form data and queues a job loading it.
+// The 'file' part holds the data set and the optional 'mapping' part the mapping of its columns;
+// the 'format' field names the format when the file extension does not tell it.
+func (c *Controller) UploadDataset(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.admin.UploadDataset"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	// The server timeouts are sized for queries; a large upload over a slow link takes longer.
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(c.uploadTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		log.Warn("failed to extend read deadline", sl.Err(err))
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		log.Warn("failed to extend write deadline", sl.Err(err))
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, c.maxUpload)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		log.Error("failed to parse form", sl.Err(err))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			resp := fmt.Sprintf("Upload is larger than %d bytes.", c.maxUpload)
+			response.Render(w, r, response.ErrBadRequest(resp))
+			return
+		}
+		response.Render(w, r, response.ErrBadRequest("Request body must be multipart form data."))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Error("failed to read file", sl.Err(err))
+		response.Render(w, r, response.ErrInvalidParam("file", "must hold the data set"))
+		return
+	}
+	defer file.Close()
+	upload := &usecase.DatasetUpload{
+		Filename: header.Filename,
+		Format:   r.FormValue("format"),
+		Data:     file,
+	}
+	if mapping, _, err := r.FormFile("mapping"); err == nil {
+		defer mapping.Close()
+		upload.Mapping = mapping
+	}
+	log.Info("request received", slog.String("filename", upload.Filename), slog.Int64("bytes", header.Size),
+		slog.String("format", upload.Format), slog.Bool("mapping", upload.Mapping != nil))
+
+	job, err := c.uc.SubmitDataset(r.Context(), upload)
+	if err != nil {
+		log.Error("failed to submit data set", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+	log.Info("job queued", slog.String("job", job.ID))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/admin/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(job)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		return
+	}
+}
+
+func (c *Controller) Job(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.admin.Job"
+	id := chi.URLParam(r, "id")
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+		slog.String("job", id),
+	)
+	log.Info("request received")
+
+	job, err := c.uc.Job(r.Context(), id)
+	if errors.Is(err, usecase.ErrJobNotFound) {
+		log.Error("job not found", sl.Err(err))
+		resp := fmt.Sprintf("No job with ID '%s'.", id)
+		response.Render(w, r, response.ErrNotFoundText(resp))
+		return
+	} else if err != nil {
+		log.Error("failed to get job", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(job)
+	if err != nil {
+		log.Error("failed to encode response", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
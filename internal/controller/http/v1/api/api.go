@@ -1,13 +1,19 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 	"html/template"
+	"io"
 	"log/slog"
+	"nearestPlaces/internal/controller/http/middleware/clientip"
+	"nearestPlaces/internal/entity"
 	"nearestPlaces/internal/lib/api/response"
+	"nearestPlaces/internal/lib/geo"
+	"nearestPlaces/internal/lib/geoparam"
 	"nearestPlaces/internal/lib/logger/sl"
 	"nearestPlaces/internal/usecase"
 	"net/http"
@@ -16,19 +22,25 @@ import (
 
 type APIer interface {
 	Places(w http.ResponseWriter, r *http.Request)
+	Within(w http.ResponseWriter, r *http.Request)
+	Clusters(w http.ResponseWriter, r *http.Request)
 	Recommend(w http.ResponseWriter, r *http.Request)
 	Paginate(http.ResponseWriter, *http.Request)
+	Search(w http.ResponseWriter, r *http.Request)
+	Suggest(w http.ResponseWriter, r *http.Request)
 }
 
 type Controller struct {
-	log *slog.Logger
-	uc  usecase.Restaurateur
+	log     *slog.Logger
+	uc      usecase.Restaurateur
+	locator usecase.Locator
 }
 
-func New(log *slog.Logger, uc usecase.Restaurateur) *Controller {
+func New(log *slog.Logger, uc usecase.Restaurateur, locator usecase.Locator) *Controller {
 	return &Controller{
-		log: log,
-		uc:  uc,
+		log:     log,
+		uc:      uc,
+		locator: locator,
 	}
 }
 
@@ -38,27 +50,30 @@ func (c *Controller) Places(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 		slog.String("request_id", middleware.GetReqID(r.Context())),
 	)
+	if !r.URL.Query().Has("page") {
+		c.placesByCursor(w, r, log)
+		return
+	}
 	page, err := strconv.Atoi(r.URL.Query().Get("page"))
 	if err != nil || page < 1 {
 		log.Error("invalid page number", slog.String("page", r.URL.Query().Get("page")))
-		resp := fmt.Sprintf("Invalid 'page' value: '%d'.", page)
-		render.Render(w, r, response.ErrBadRequest(resp))
+		response.Render(w, r, response.ErrInvalidParam("page", "must be a positive integer"))
 		return
 	}
 	log.Info("request received", slog.String("page", r.URL.Query().Get("page")))
 
-	pageInfo, err := c.uc.GetPage(page)
+	pageInfo, err := c.uc.GetPage(r.Context(), page)
 	if err != nil {
 		log.Error("failed to get places: ", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.Render(w, r, response.ErrFromDomain(err))
 		return
 	}
 	log.Info("pages received from storage")
 
 	if page > pageInfo.LastPage {
 		log.Error("page parameter is too large", slog.String("page", r.URL.Query().Get("page")))
-		resp := fmt.Sprintf("Invalid 'page' value: '%d'.", page)
-		render.Render(w, r, response.ErrBadRequest(resp))
+		reason := fmt.Sprintf("must not exceed %d", pageInfo.LastPage)
+		response.Render(w, r, response.ErrInvalidParam("page", reason))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -66,7 +81,147 @@ func (c *Controller) Places(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(pageInfo)
 	if err != nil {
 		log.Error("failed to encode response: ", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+// placesByCursor serves /api/places when no page number is given: the listing is paginated
+// with opaque cursors taken from the 'cursor' query parameter.
+func (c *Controller) placesByCursor(w http.ResponseWriter, r *http.Request, log *slog.Logger) {
+	cursor := r.URL.Query().Get("cursor")
+	log.Info("request received", slog.String("cursor", cursor))
+
+	pageInfo, err := c.uc.GetPageByCursor(r.Context(), cursor)
+	if errors.Is(err, entity.ErrInvalidCursor) {
+		log.Error("invalid cursor", sl.Err(err))
+		response.Render(w, r, response.ErrInvalidParam("cursor", "is invalid or expired"))
+		return
+	} else if err != nil {
+		log.Error("failed to get places: ", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+	log.Info("page received from storage")
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(pageInfo)
+	if err != nil {
+		log.Error("failed to encode response: ", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+// Within serves the places inside the area given by the 'bbox' query parameter
+// or, when it is absent, by a GeoJSON Polygon or MultiPolygon in the request body.
+func (c *Controller) Within(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.places.Within"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	page := 1
+	if r.URL.Query().Has("page") {
+		var err error
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			log.Error("invalid page number", slog.String("page", r.URL.Query().Get("page")))
+			response.Render(w, r, response.ErrInvalidParam("page", "must be a positive integer"))
+			return
+		}
+	}
+	area := &entity.Area{}
+	if r.URL.Query().Has("bbox") {
+		bbox, err := geoparam.ParseBBox(r.URL.Query().Get("bbox"))
+		if err != nil {
+			log.Error("failed to parse bbox", sl.Err(err))
+			response.Render(w, r, response.ErrInvalidParam("bbox", err.Error()))
+			return
+		}
+		area.BBox = bbox
+	} else if r.Body != nil && r.Body != http.NoBody {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAreaBody))
+		if err != nil {
+			log.Error("failed to read request body", sl.Err(err))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				response.Render(w, r, response.ErrBadRequest("Request body is too large."))
+				return
+			}
+			response.Render(w, r, response.ErrBadRequest("Request body could not be read."))
+			return
+		}
+		area, err = decodeArea(data)
+		if err != nil {
+			log.Error("failed to decode area", sl.Err(err))
+			resp := fmt.Sprintf("Invalid area: %s.", err.Error())
+			response.Render(w, r, response.ErrBadRequest(resp))
+			return
+		}
+	}
+	log.Info("request received", slog.Bool("bbox", area.BBox != nil), slog.Int("polygons", len(area.Polygons)))
+
+	pageInfo, err := c.uc.GetPageWithin(r.Context(), area, page)
+	if err != nil {
+		log.Error("failed to get places: ", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+	log.Info("page received from storage")
+
+	if page > pageInfo.LastPage {
+		log.Error("page parameter is too large", slog.Int("page", page))
+		reason := fmt.Sprintf("must not exceed %d", pageInfo.LastPage)
+		response.Render(w, r, response.ErrInvalidParam("page", reason))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(pageInfo)
+	if err != nil {
+		log.Error("failed to encode response: ", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+// Clusters serves the places of a map viewport given by 'bbox', grouped for the 'zoom' level.
+func (c *Controller) Clusters(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.places.Clusters"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	zoom, err := strconv.Atoi(r.URL.Query().Get("zoom"))
+	if err != nil {
+		log.Error("failed to parse zoom")
+		response.Render(w, r, response.ErrInvalidParam("zoom", "must be an integer"))
+		return
+	}
+	var bbox *entity.BBox
+	if r.URL.Query().Has("bbox") {
+		bbox, err = geoparam.ParseBBox(r.URL.Query().Get("bbox"))
+		if err != nil {
+			log.Error("failed to parse bbox", sl.Err(err))
+			response.Render(w, r, response.ErrInvalidParam("bbox", err.Error()))
+			return
+		}
+	}
+	log.Info("request received", slog.Int("zoom", zoom), slog.String("bbox", r.URL.Query().Get("bbox")))
+
+	result, err := c.uc.GetClusters(r.Context(), bbox, zoom)
+	if err != nil {
+		log.Error("failed to get clusters", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.Error("failed to encode response: ", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
 		return
 	}
 }
@@ -80,41 +235,42 @@ func (c *Controller) Paginate(w http.ResponseWriter, r *http.Request) {
 	p, err := strconv.Atoi(r.URL.Query().Get("page"))
 	if err != nil || p < 1 {
 		log.Error("invalid page number", slog.String("page", r.URL.Query().Get("page")))
-		w.WriteHeader(http.StatusBadRequest)
-		resp := fmt.Sprintf("Invalid 'page' value: '%d'.", p)
-		w.Write([]byte(resp))
+		response.RenderHTML(w, r, response.ErrInvalidParam("page", "must be a positive integer"))
 		return
 	}
 	log.Info("request received", slog.String("page", r.URL.Query().Get("page")))
 
-	pageInfo, err := c.uc.GetPage(p)
+	pageInfo, err := c.uc.GetPage(r.Context(), p)
 	if err != nil {
 		log.Error("failed to get places: ", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.RenderHTML(w, r, response.ErrFromDomain(err))
 		return
 	}
 	log.Info("pages received from storage")
 
 	if p > pageInfo.LastPage {
 		log.Error("page parameter is too large", slog.String("page", r.URL.Query().Get("page")))
-		w.WriteHeader(http.StatusBadRequest)
-		resp := fmt.Sprintf("Invalid 'page' value: '%d'.", p)
-		w.Write([]byte(resp))
+		reason := fmt.Sprintf("must not exceed %d", pageInfo.LastPage)
+		response.RenderHTML(w, r, response.ErrInvalidParam("page", reason))
 		return
 	}
 	tmpl, err := template.New("index.html").ParseFiles("templates/index.html")
 	if err != nil {
 		log.Error("failed to parse template: ", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.RenderHTML(w, r, response.ErrInternal())
 		return
 	}
 
-	err = tmpl.Execute(w, pageInfo)
+	// The page is rendered into a buffer first, so that a failing template still gets a proper error page.
+	var page bytes.Buffer
+	err = tmpl.Execute(&page, pageInfo)
 	if err != nil {
 		log.Error("failed to render template: ", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.RenderHTML(w, r, response.ErrInternal())
 		return
 	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
 	log.Info("request executed")
 }
 
@@ -124,24 +280,135 @@ func (c *Controller) Recommend(w http.ResponseWriter, r *http.Request) {
 		slog.String("op", op),
 		slog.String("request_id", middleware.GetReqID(r.Context())),
 	)
-	lat, err := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
-	if err != nil || lat < 0 {
-		log.Error("failed to parse latitude")
-		resp := fmt.Sprintf("Invalid 'lat' value: '%v'.", r.URL.Query().Get("lat"))
-		render.Render(w, r, response.ErrBadRequest(resp))
+	point, err := geoparam.FromQuery(r.URL.Query())
+	if err != nil {
+		log.Error("failed to parse location", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
 		return
 	}
-	lon, err := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
-	if err != nil || lon < 0 {
-		log.Error("failed to parse longitude")
-		resp := fmt.Sprintf("Invalid 'lon' value: '%v'.", r.URL.Query().Get("lon"))
-		render.Render(w, r, response.ErrBadRequest(resp))
-		return
+	var origin *entity.Origin
+	if point != nil {
+		origin = &entity.Origin{Point: *point, Source: entity.OriginRequest}
+	} else {
+		origin, err = c.locator.Locate(r.Context(), clientip.FromContext(r.Context()))
+		if errors.Is(err, entity.ErrNotFound) {
+			log.Error("location is missing and cannot be approximated", sl.Err(err))
+			response.Render(w, r, response.ErrInvalidParams(
+				response.InvalidParam{Name: "lat", Reason: "is required unless 'll' or 'geohash' is given"},
+				response.InvalidParam{Name: "lon", Reason: "is required unless 'll' or 'geohash' is given"},
+			))
+			return
+		} else if err != nil {
+			log.Error("failed to approximate location", sl.Err(err))
+			response.Render(w, r, response.ErrFromDomain(err))
+			return
+		}
+	}
+	var limit int
+	if r.URL.Query().Has("limit") {
+		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit < 1 {
+			log.Error("failed to parse limit")
+			response.Render(w, r, response.ErrInvalidParam("limit", "must be a positive integer"))
+			return
+		}
+	}
+	var radius float64
+	if r.URL.Query().Has("radius") {
+		radius, err = geo.ParseDistance(r.URL.Query().Get("radius"))
+		if err != nil || radius == 0 {
+			log.Error("failed to parse radius")
+			response.Render(w, r, response.ErrInvalidParam("radius", "must be a positive distance, e.g. 500m or 2km"))
+			return
+		}
 	}
-	result, err := c.uc.GetClosestRestaurants(lat, lon)
+	result, err := c.uc.GetClosestRestaurants(r.Context(), origin, limit, radius)
 	if err != nil {
 		log.Error("failed to get closest restaurants", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.Error("failed to encode response: ", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+func (c *Controller) Search(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.search.Search"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	text := r.URL.Query().Get("q")
+	page := 1
+	if r.URL.Query().Has("page") {
+		var err error
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			log.Error("invalid page number", slog.String("page", r.URL.Query().Get("page")))
+			response.Render(w, r, response.ErrInvalidParam("page", "must be a positive integer"))
+			return
+		}
+	}
+	near, err := geoparam.FromQuery(r.URL.Query())
+	if err != nil {
+		log.Error("failed to parse location", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+	log.Info("request received", slog.String("q", text), slog.Int("page", page))
+
+	result, err := c.uc.Search(r.Context(), text, near, page)
+	if err != nil {
+		log.Error("failed to search places", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
+		return
+	}
+
+	if page > result.LastPage {
+		log.Error("page parameter is too large", slog.Int("page", page))
+		reason := fmt.Sprintf("must not exceed %d", result.LastPage)
+		response.Render(w, r, response.ErrInvalidParam("page", reason))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		log.Error("failed to encode response: ", sl.Err(err))
+		response.Render(w, r, response.ErrInternal())
+		return
+	}
+}
+
+func (c *Controller) Suggest(w http.ResponseWriter, r *http.Request) {
+	const op = "controller.suggest.Suggest"
+	log := c.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+	prefix := r.URL.Query().Get("prefix")
+	var size int
+	if r.URL.Query().Has("size") {
+		var err error
+		size, err = strconv.Atoi(r.URL.Query().Get("size"))
+		if err != nil || size < 1 {
+			log.Error("failed to parse size")
+			response.Render(w, r, response.ErrInvalidParam("size", "must be a positive integer"))
+			return
+		}
+	}
+	log.Info("request received", slog.String("prefix", prefix))
+
+	result, err := c.uc.Suggest(r.Context(), prefix, size)
+	if err != nil {
+		log.Error("failed to get suggestions", sl.Err(err))
+		response.Render(w, r, response.ErrFromDomain(err))
 		return
 	}
 
@@ -149,7 +416,7 @@ func (c *Controller) Recommend(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(result)
 	if err != nil {
 		log.Error("failed to encode response: ", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.Render(w, r, response.ErrInternal())
 		return
 	}
 }
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nearestPlaces/internal/entity"
+)
+
+// maxAreaBody limits the size of a GeoJSON geometry sent to /api/places/within.
+const maxAreaBody = 1 << 20
+
+// geometry is a GeoJSON geometry object or a Feature wrapping one.
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geometry       `json:"geometry"`
+}
+
+// decodeArea reads a GeoJSON Polygon or MultiPolygon, bare or as a Feature.
+func decodeArea(data []byte) (*entity.Area, error) {
+	var g geometry
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	if g.Type == "Feature" {
+		if g.Geometry == nil {
+			return nil, errors.New("feature has no geometry")
+		}
+		g = *g.Geometry
+	}
+
+	var polygons [][][][]float64
+	switch g.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		polygons = append(polygons, polygon)
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q, expected Polygon or MultiPolygon", g.Type)
+	}
+	if len(polygons) == 0 {
+		return nil, errors.New("geometry has no polygons")
+	}
+
+	area := &entity.Area{Polygons: make([]entity.Polygon, 0, len(polygons))}
+	for _, rings := range polygons {
+		if len(rings) == 0 {
+			return nil, errors.New("polygon has no rings")
+		}
+		polygon := make(entity.Polygon, 0, len(rings))
+		for _, positions := range rings {
+			ring, err := toRing(positions)
+			if err != nil {
+				return nil, err
+			}
+			polygon = append(polygon, ring)
+		}
+		area.Polygons = append(area.Polygons, polygon)
+	}
+	return area, nil
+}
+
+// toRing converts GeoJSON positions, [lon, lat] with an optional altitude, to a closed ring.
+func toRing(positions [][]float64) (entity.Ring, error) {
+	if len(positions) < 4 {
+		return nil, errors.New("a linear ring needs at least four positions")
+	}
+	ring := make(entity.Ring, 0, len(positions))
+	for _, pos := range positions {
+		if len(pos) < 2 {
+			return nil, errors.New("a position needs longitude and latitude")
+		}
+		p := entity.Point{Lon: pos[0], Lat: pos[1]}
+		if p.Lon < -180 || p.Lon > 180 || p.Lat < -90 || p.Lat > 90 {
+			return nil, fmt.Errorf("position [%g, %g] is out of range", p.Lon, p.Lat)
+		}
+		ring = append(ring, p)
+	}
+	if ring[0] != ring[len(ring)-1] {
+		return nil, errors.New("a linear ring must end where it starts")
+	}
+	return ring, nil
+}
@@ -2,8 +2,8 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 	"log/slog"
 	"nearestPlaces/internal/lib/api/response"
 	"nearestPlaces/internal/lib/logger/sl"
@@ -27,10 +27,15 @@ func New(log *slog.Logger, uc usecase.Auther) *Controller {
 	}
 }
 
+// AdminKeyHeader carries the admin key in exchange for which a token for the admin API is issued.
+const AdminKeyHeader = "X-Admin-Key"
+
 type Response struct {
 	Token string `json:"token"`
 }
 
+// GetToken issues a token. A request presenting the admin key in the X-Admin-Key header
+// gets one carrying the admin claim; a wrong key is answered with 403.
 func (c *Controller) GetToken(w http.ResponseWriter, r *http.Request) {
 	const op = "controller.auth.GetToken"
 	log := c.log.With(
@@ -38,10 +43,21 @@ func (c *Controller) GetToken(w http.ResponseWriter, r *http.Request) {
 		slog.String("request_id", middleware.GetReqID(r.Context())),
 	)
 	log.Info("request received")
-	token, err := c.uc.GetToken()
+	var token string
+	var err error
+	if key := r.Header.Get(AdminKeyHeader); key != "" {
+		token, err = c.uc.GetAdminToken(r.Context(), key)
+	} else {
+		token, err = c.uc.GetToken(r.Context())
+	}
+	if errors.Is(err, usecase.ErrAdminKey) {
+		log.Warn("admin token refused", sl.Err(err))
+		response.Render(w, r, response.ErrForbidden())
+		return
+	}
 	if err != nil {
 		log.Error("failed to get token", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.Render(w, r, response.ErrFromDomain(err))
 		return
 	}
 	log.Info("token generated")
@@ -53,7 +69,7 @@ func (c *Controller) GetToken(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(resp)
 	if err != nil {
 		log.Error("failed to encode response", sl.Err(err))
-		render.Render(w, r, response.ErrInternal())
+		response.Render(w, r, response.ErrInternal())
 		return
 	}
 }
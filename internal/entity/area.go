@@ -0,0 +1,70 @@
+package entity
+
+// BBox is a rectangle bounded by two parallels and two meridians, in degrees.
+// West is greater than East when the box crosses the antimeridian.
+type BBox struct {
+	West  float64 `json:"west"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	North float64 `json:"north"`
+}
+
+func (b *BBox) Contains(p Point) bool {
+	if p.Lat < b.South || p.Lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return p.Lon >= b.West && p.Lon <= b.East
+	}
+	return p.Lon >= b.West || p.Lon <= b.East
+}
+
+// Ring is a closed line: its first and last points are the same.
+type Ring []Point
+
+// Contains tells whether the point lies inside the ring. Edges are straight lines
+// in the latitude/longitude plane, as in the geo_polygon query of Elasticsearch.
+func (r Ring) Contains(p Point) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Polygon is an outer ring followed by the rings of its holes.
+type Polygon []Ring
+
+func (pg Polygon) Contains(p Point) bool {
+	if len(pg) == 0 || !pg[0].Contains(p) {
+		return false
+	}
+	for _, hole := range pg[1:] {
+		if hole.Contains(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Area is the region of an area query: a bounding box or, when BBox is nil, the union of polygons.
+type Area struct {
+	BBox     *BBox
+	Polygons []Polygon
+}
+
+func (a *Area) Contains(p Point) bool {
+	if a.BBox != nil {
+		return a.BBox.Contains(p)
+	}
+	for _, pg := range a.Polygons {
+		if pg.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package entity
+
+// Cluster is a group of places falling into one map tile, keyed "zoom/x/y".
+// Center is the centroid of the places rather than the middle of the tile.
+type Cluster struct {
+	Tile   string `json:"tile"`
+	Count  int    `json:"count"`
+	Center Point  `json:"center"`
+}
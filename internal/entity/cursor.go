@@ -0,0 +1,8 @@
+package entity
+
+// Cursors holds opaque tokens pointing to the neighbouring pages of a cursor-paginated listing.
+// An empty token means there is no page in that direction.
+type Cursors struct {
+	Next string
+	Prev string
+}
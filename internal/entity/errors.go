@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Domain errors tell kinds of failures apart. Storages and usecases wrap them,
+// so that callers can decide with errors.Is how to react, e.g. whether to retry.
+var (
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrNotFound        = errors.New("not found")
+	ErrUnavailable     = errors.New("service unavailable")
+	ErrTimeout         = errors.New("request took too long")
+	ErrInternal        = errors.New("internal server error")
+)
+
+var ErrInvalidCursor = fmt.Errorf("%w: invalid or expired cursor", ErrInvalidArgument)
+
+// ParamError is an invalid argument caused by a single request parameter.
+type ParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: '%s' %s", ErrInvalidArgument, e.Param, e.Reason)
+}
+
+func (e *ParamError) Unwrap() error {
+	return ErrInvalidArgument
+}
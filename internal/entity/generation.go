@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// Generation is one timestamped build of the places index.
+// Exactly one generation is current, i.e. serves queries.
+type Generation struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Docs    int       `json:"docs"`
+	Current bool      `json:"current"`
+}
+
+// GenerationLayout is the timestamp suffix of generation names, e.g. places-20241018-153000.123.
+const GenerationLayout = "20060102-150405.000"
+
+// GenerationName names the generation of the alias created at t.
+func GenerationName(alias string, t time.Time) string {
+	return alias + "-" + t.UTC().Format(GenerationLayout)
+}
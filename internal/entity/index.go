@@ -0,0 +1,18 @@
+package entity
+
+import "encoding/json"
+
+// IndexInfo describes an index of the storage: how many documents it holds,
+// its size on disk in bytes and the mappings of its fields.
+type IndexInfo struct {
+	Name     string          `json:"name"`
+	Docs     int             `json:"docs"`
+	Bytes    int64           `json:"bytes"`
+	Mappings json.RawMessage `json:"mappings"`
+}
+
+// Alias is a name queries are sent to and the indices it points to.
+type Alias struct {
+	Name    string   `json:"name"`
+	Indices []string `json:"indices"`
+}
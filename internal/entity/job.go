@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// JobState is where an ingestion job is in its life.
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// Ingestion jobs are started by an upload, or by the watcher when the configured data set changes.
+const (
+	TriggerUpload = "upload"
+	TriggerWatch  = "watch"
+)
+
+// JobProgress counts the rows an ingestion job has read and rejected so far.
+type JobProgress struct {
+	Rows       int   `json:"rows"`
+	Rejected   int   `json:"rejected"`
+	BytesRead  int64 `json:"bytes_read"`
+	BytesTotal int64 `json:"bytes_total"`
+}
+
+// Job is the load of an uploaded data set, or of the configured one after it changed, run in the background.
+// Index is the generation it fills; Report is the path of its rejection report,
+// of which Rejections holds the first rows. Sync is set for jobs run in sync mode.
+type Job struct {
+	ID         string       `json:"id"`
+	State      JobState     `json:"state"`
+	Trigger    string       `json:"trigger"`
+	Filename   string       `json:"filename"`
+	Format     string       `json:"format"`
+	Mode       string       `json:"mode"`
+	Index      string       `json:"index,omitempty"`
+	Progress   JobProgress  `json:"progress"`
+	Report     string       `json:"report,omitempty"`
+	Rejections []Rejection  `json:"rejections,omitempty"`
+	Sync       *SyncSummary `json:"sync,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
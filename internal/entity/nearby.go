@@ -0,0 +1,12 @@
+package entity
+
+// NearbyPlace is a restaurant found around a point together with where it lies relative to that point.
+type NearbyPlace struct {
+	*Restaurant
+	// Distance is the great-circle distance from the point in metres.
+	Distance     float64 `json:"distance"`
+	DistanceText string  `json:"distance_text"`
+	// Bearing is the compass bearing from the point in degrees, clockwise from north.
+	Bearing   float64 `json:"bearing"`
+	Direction string  `json:"direction"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+// Origin sources tell how the point a nearby search starts from was obtained.
+const (
+	// OriginRequest is a point the client sent.
+	OriginRequest = "request"
+	// OriginGeoIP is a point approximated from the client IP address.
+	OriginGeoIP = "geoip"
+)
+
+// Origin is the point a nearby search starts from.
+type Origin struct {
+	Point
+	Source      string `json:"source"`
+	Approximate bool   `json:"approximate"`
+	// AccuracyRadius is how far in metres the client may be from an approximated point.
+	AccuracyRadius float64 `json:"accuracy_radius,omitempty"`
+	City           string  `json:"city,omitempty"`
+	Country        string  `json:"country,omitempty"`
+}
@@ -0,0 +1,7 @@
+package entity
+
+// Point is a geographic location in degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
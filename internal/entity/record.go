@@ -0,0 +1,26 @@
+package entity
+
+import "errors"
+
+// ErrSourceBroken marks the record at which a source could not be read any further. No records follow it.
+var ErrSourceBroken = errors.New("source cannot be read further")
+
+// Record is a row of a data set on its way to the index: the place parsed from it
+// or the reason it could not be parsed.
+type Record struct {
+	// Line is the line of the source the row starts at, counting from 1.
+	Line int
+	// Offset is how many bytes of the source have been read up to the end of the row.
+	Offset int64
+	// Raw is the text of the row as it is in the source.
+	Raw   string
+	Place *Restaurant
+	Err   error
+}
+
+// Rejection is a row left out of a load and the reason why.
+type Rejection struct {
+	Line   int    `json:"line"`
+	Raw    string `json:"raw"`
+	Reason string `json:"reason"`
+}
@@ -1,5 +1,12 @@
 package entity
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
 type Restaurant struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -9,4 +16,50 @@ type Restaurant struct {
 		Lon float64 `json:"lon"`
 		Lat float64 `json:"lat"`
 	} `json:"location"`
+	// Extra holds the columns of the data set that are not mapped onto the fields above.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// ContentHash identifies the content of the place: places hash alike only when all their fields are equal.
+func (r *Restaurant) ContentHash() string {
+	b, _ := json.Marshal(r)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
+// The markers below follow the addresses of the Moscow open data set, transliterated as in datasets/data.csv
+// or in Cyrillic; addresses written otherwise yield no street, and so no street suggestions.
+
+// houseMarkers start the address component holding the house number.
+var houseMarkers = []string{"dom ", "domovladenie ", "vladenie ", "дом ", "д. ", "владение "}
+
+// settlementMarkers start address components naming a city rather than a street.
+var settlementMarkers = []string{"gorod ", "город ", "г. "}
+
+// Street extracts the street from an address like "gorod Moskva, ulitsa Grekova, dom 3, korpus 1",
+// taking the component in front of the house number. It is empty when the address has no such component.
+// Only Moscow-style addresses are understood; see houseMarkers and settlementMarkers.
+func (r *Restaurant) Street() string {
+	parts := strings.Split(r.Address, ",")
+	for i := 1; i < len(parts); i++ {
+		if !hasAnyPrefix(strings.TrimSpace(parts[i]), houseMarkers) {
+			continue
+		}
+		street := strings.TrimSpace(parts[i-1])
+		if hasAnyPrefix(street, settlementMarkers) {
+			return ""
+		}
+		return street
+	}
+	return ""
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	s = strings.ToLower(s)
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,35 @@
+package entity
+
+import "testing"
+
+func TestRestaurant_Street(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		// rows of datasets/data.csv, named by their ID
+		{name: "0", address: "gorod Moskva, ulitsa Egora Abakumova, dom 9", want: "ulitsa Egora Abakumova"},
+		{name: "1", address: "gorod Moskva, ulitsa Talalihina, dom 2/1, korpus 1", want: "ulitsa Talalihina"},
+		{name: "2", address: "gorod Moskva, Abel'manovskaja ulitsa, dom 6", want: "Abel'manovskaja ulitsa"},
+		{name: "111", address: "gorod Moskva, Nahimovskij prospekt, vladenie 75A", want: "Nahimovskij prospekt"},
+		{name: "1109", address: "gorod Moskva, gorod Zelenograd, ulitsa Junosti, dom 11", want: "ulitsa Junosti"},
+		{name: "1468", address: "gorod Moskva, poselenie Vnukovskoe, ulitsa Letchika Ul'janina, dom 2", want: "ulitsa Letchika Ul'janina"},
+		// the component in front of the house number is taken, even when it is a landmark of the street
+		{name: "6649", address: "gorod Moskva, poselenie Moskovskij, Kievskoe shosse, 22-j kilometr, domovladenie 6, stroenie 1", want: "22-j kilometr"},
+		// a house numbered within a town has no street
+		{name: "Zelenograd", address: "gorod Moskva, gorod Zelenograd, korpus 435", want: ""},
+		{name: "town house", address: "gorod Moskva, gorod Zelenograd, dom 5", want: ""},
+		{name: "cyrillic", address: "г. Москва, ул. Тверская, д. 7", want: "ул. Тверская"},
+		{name: "no house", address: "gorod Moskva, ulitsa Arbat", want: ""},
+		{name: "empty", address: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Restaurant{Address: tt.address}
+			if got := r.Street(); got != tt.want {
+				t.Errorf("Street() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package entity
+
+// SearchHit is a restaurant matching a full-text query.
+type SearchHit struct {
+	*Restaurant
+	Score float64 `json:"score"`
+	// Highlights maps a field name to its fragments with the matched terms wrapped in <em> tags.
+	// The fragments are HTML-escaped.
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
@@ -0,0 +1,30 @@
+package entity
+
+import "strings"
+
+const (
+	SuggestionName   = "name"
+	SuggestionStreet = "street"
+)
+
+// Suggestion is a completion offered while a user types a place name or a street.
+// ID refers to a place with that name or on that street.
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// maxCompletionTails limits how many word-starting tails of a text are completion inputs.
+const maxCompletionTails = 5
+
+// CompletionInputs returns the text and its tails starting at every following word, up to five of them,
+// so that "Kafe Akademija" is suggested for "aka" as well as for "kaf". Every storage indexes these inputs.
+func CompletionInputs(text string) []string {
+	words := strings.Fields(text)
+	inputs := make([]string, 0, min(len(words), maxCompletionTails))
+	for i := 0; i < len(words) && i < maxCompletionTails; i++ {
+		inputs = append(inputs, strings.Join(words[i:], " "))
+	}
+	return inputs
+}
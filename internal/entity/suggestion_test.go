@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletionInputs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: []string{}},
+		{name: "one word", text: "SMETANA", want: []string{"SMETANA"}},
+		{name: "tails", text: "Kafe  «Akademija»", want: []string{"Kafe «Akademija»", "«Akademija»"}},
+		{name: "at most five", text: "a b c d e f g", want: []string{"a b c d e f g", "b c d e f g", "c d e f g", "d e f g", "e f g"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompletionInputs(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompletionInputs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
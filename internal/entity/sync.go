@@ -0,0 +1,37 @@
+package entity
+
+// ChangeOp is what a sync does to a place of the index.
+type ChangeOp string
+
+const (
+	ChangeAdd    ChangeOp = "add"
+	ChangeModify ChangeOp = "modify"
+	ChangeRemove ChangeOp = "remove"
+)
+
+// Change is an operation a sync sends to the index. Place is nil for removals.
+type Change struct {
+	Op    ChangeOp
+	ID    string
+	Place *Restaurant
+}
+
+// ChangeSet counts the places a sync treated alike and names some of them.
+type ChangeSet struct {
+	Count  int      `json:"count"`
+	Sample []string `json:"sample,omitempty"`
+}
+
+// SyncSummary tells how a sync brought the index in line with the data set.
+// Kept are the indexed places whose row was rejected, left as they were. Partial is set for a sync that
+// failed while its changes were applied, which left the generation with only some of them.
+type SyncSummary struct {
+	Index     string    `json:"index"`
+	Added     ChangeSet `json:"added"`
+	Modified  ChangeSet `json:"modified"`
+	Removed   ChangeSet `json:"removed"`
+	Unchanged ChangeSet `json:"unchanged"`
+	Kept      ChangeSet `json:"kept"`
+	Rejected  int       `json:"rejected"`
+	Partial   bool      `json:"partial,omitempty"`
+}
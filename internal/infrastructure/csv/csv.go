@@ -2,68 +2,196 @@ package csv
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"nearestPlaces/internal/entity"
 	"os"
-	"strconv"
+	"strings"
+	"sync"
 )
 
 type Parser struct {
+	workers int
+	buffer  int
 }
 
-func New() *Parser {
-	return &Parser{}
+// New creates a parser that parses rows on the given number of workers,
+// with up to buffer rows waiting between reading, parsing and the consumer.
+func New(workers, buffer int) *Parser {
+	return &Parser{
+		workers: max(workers, 1),
+		buffer:  max(buffer, 1),
+	}
+}
+
+// job is a row read from the file. Its result is sent to res, so that rows
+// parsed concurrently can still be passed on in the order of the file.
+type job struct {
+	line   int
+	offset int64
+	raw    string
+	fields []string
+	layout *layout
+	res    chan entity.Record
 }
 
-func (p *Parser) ParseCSV(filename string) ([]*entity.Restaurant, error) {
-	var res []*entity.Restaurant
+// Import reads a delimited file with a header line and sends its rows in order. The columns are mapped
+// onto places by the mapping kept next to the file, see ReadMapping; columns it leaves out become extra attributes.
+// It returns the size of the file, so that progress can be told from the offsets of the records.
+// Rows that are not valid CSV are sent with an error and skipped. The channel is closed after the last row,
+// after the file fails to be read, or when ctx is done.
+func (p *Parser) Import(ctx context.Context, filename string) (<-chan entity.Record, int64, error) {
+	mapping, err := ReadMapping(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	comma, err := mapping.comma()
+	if err != nil {
+		return nil, 0, fmt.Errorf("mapping of %s: %w", filename, err)
+	}
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
+		return nil, 0, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat file %s: %w", filename, err)
+	}
+
+	jobs := make(chan *job, p.buffer)
+	queue := make(chan *job, p.buffer)
+	out := make(chan entity.Record, p.buffer)
+	src, err := newSource(file, mapping.Encoding)
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+
+	go func() {
+		defer file.Close()
+		defer close(jobs)
+		defer close(queue)
+		read(ctx, src, mapping, comma, jobs, queue)
+	}()
+
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.res <- parseJob(j)
+			}
+		}()
 	}
-	defer file.Close()
 
-	r := csv.NewReader(bufio.NewReader(file))
-	r.Comma = '\t'
-	_, _ = r.Read()
+	go func() {
+		defer close(out)
+		for j := range queue {
+			select {
+			case out <- <-j.res:
+			case <-ctx.Done():
+				// Let the readers and workers run out of rows.
+				for range queue {
+				}
+				wg.Wait()
+				return
+			}
+		}
+		wg.Wait()
+	}()
+	return out, info.Size(), nil
+}
+
+// read splits the file into rows and hands them out both to the workers and, in order, to the queue.
+func read(ctx context.Context, text *source, mapping *Mapping, comma rune, jobs, queue chan<- *job) {
+	src := &rawReader{r: text}
+	r := csv.NewReader(bufio.NewReader(src))
+	r.Comma = comma
+	// The number of fields is checked by the layout, so that such rows are reported like any other invalid row.
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		if err != io.EOF {
+			queue <- failedJob(1, text.offset(r.InputOffset()), src.cut(r.InputOffset()), fmt.Errorf("%w: failed to read header: %w", entity.ErrSourceBroken, err))
+		}
+		return
+	}
+	raw := src.cut(r.InputOffset())
+	l, err := newLayout(mapping, header)
+	if err != nil {
+		queue <- failedJob(1, text.offset(r.InputOffset()), raw, fmt.Errorf("%w: failed to map header: %w", entity.ErrSourceBroken, err))
+		return
+	}
 	for {
-		line, err := r.Read()
+		fields, err := r.Read()
 		if err == io.EOF {
-			break
+			return
 		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read line: %w", err)
+		raw := src.cut(r.InputOffset())
+		offset := text.offset(r.InputOffset())
+		var j *job
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			j = failedJob(parseErr.StartLine, offset, raw, parseErr.Err)
+		case err != nil:
+			j = failedJob(0, offset, raw, fmt.Errorf("%w: %w", entity.ErrSourceBroken, err))
+		default:
+			line, _ := r.FieldPos(0)
+			j = &job{line: line, offset: offset, raw: raw, fields: fields, layout: l, res: make(chan entity.Record, 1)}
+		}
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+			return
+		}
+		if parseErr != nil {
+			continue
 		}
-		data, err := parseLine(line)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse line \"%s\": %w", line, err)
+			return
 		}
-		res = append(res, data)
+		jobs <- j
 	}
-	return res, nil
 }
 
-func parseLine(line []string) (*entity.Restaurant, error) {
-	if len(line) != 6 {
-		return nil, fmt.Errorf("failed to parse line \"%s\": wrong number of fields", line)
-	}
-	lon, err := strconv.ParseFloat(line[4], 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid Longitude: %s", line[4])
-	}
-	lat, err := strconv.ParseFloat(line[5], 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid Latitude: %s", line[5])
-	}
-	return &entity.Restaurant{
-		ID:      line[0],
-		Name:    line[1],
-		Address: line[2],
-		Phone:   line[3],
-		Location: struct {
-			Lon float64 `json:"lon"`
-			Lat float64 `json:"lat"`
-		}{Lon: lon, Lat: lat}}, nil
+// failedJob is a row that could not be read. No worker gets it, its result is known already.
+func failedJob(line int, offset int64, raw string, err error) *job {
+	j := &job{line: line, offset: offset, raw: raw, res: make(chan entity.Record, 1)}
+	j.res <- entity.Record{Line: line, Offset: offset, Raw: raw, Err: err}
+	return j
+}
+
+// rawReader keeps the bytes read from the file that the CSV reader has not finished with yet,
+// so that the text of every row can be cut out of them.
+type rawReader struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+func (rr *rawReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+// cut returns the text from the end of the previous cut up to the offset, without the line break.
+func (rr *rawReader) cut(offset int64) string {
+	n := int(offset - rr.base)
+	raw := strings.TrimRight(string(rr.buf[:n]), "\r\n")
+	rr.buf = rr.buf[n:]
+	rr.base = offset
+	return raw
+}
+
+func parseJob(j *job) entity.Record {
+	rec := entity.Record{Line: j.line, Offset: j.offset, Raw: j.raw}
+	rec.Place, rec.Err = j.layout.parse(j.fields)
+	return rec
 }
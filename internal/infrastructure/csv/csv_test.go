@@ -1,16 +1,23 @@
 package csv
 
 import (
+	"context"
+	"golang.org/x/text/encoding/charmap"
 	"nearestPlaces/internal/entity"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 var sampleLine = "9\tShKOLA 735\tgorod Moskva, Aviamotornaja ulitsa, dom 51\t(495) 273-21-06\t37.72098869657803\t55.746325696672486\n"
 
-func Test_parseLine(t *testing.T) {
+func Test_layout_parse(t *testing.T) {
 	type args struct {
-		line []string
+		mapping *Mapping
+		header  []string
+		line    []string
 	}
 	tests := []struct {
 		name    string
@@ -21,6 +28,8 @@ func Test_parseLine(t *testing.T) {
 		{
 			name: "test1",
 			args: args{
+				mapping: DefaultMapping(),
+				header:  []string{"", "Name", "Address", "Phone", "Longitude", "Latitude"},
 				line: []string{
 					"9",
 					"ShKOLA 735",
@@ -41,30 +50,107 @@ func Test_parseLine(t *testing.T) {
 				}{Lon: 37.72098869657803, Lat: 55.746325696672486},
 			},
 		},
+		{
+			name: "coordinates column and extra attributes",
+			args: args{
+				mapping: &Mapping{Columns: Columns{ID: "objectid", Name: "title", Coordinates: "geo", Order: "lon,lat"}},
+				header:  []string{"title", "district", "geo", "objectid", "seats"},
+				line:    []string{"Cafe", "Centre", "37.6, 55.7", "42", ""},
+			},
+			want: &entity.Restaurant{
+				ID:   "42",
+				Name: "Cafe",
+				Location: struct {
+					Lon float64 `json:"lon"`
+					Lat float64 `json:"lat"`
+				}{Lon: 37.6, Lat: 55.7},
+				Extra: map[string]string{"district": "Centre"},
+			},
+		},
+		{
+			name: "wrong number of fields",
+			args: args{
+				mapping: DefaultMapping(),
+				header:  []string{"", "Name", "Address", "Phone", "Longitude", "Latitude"},
+				line:    []string{"9", "ShKOLA 735", "37.7", "55.7"},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseLine(tt.args.line)
+			l, err := newLayout(tt.args.mapping, tt.args.header)
+			if err != nil {
+				t.Fatalf("newLayout() error = %v", err)
+			}
+			got, err := l.parse(tt.args.line)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseLine() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseLine() got = %v, want %v", got, tt.want)
+				t.Errorf("parse() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestParseCSV(t *testing.T) {
+func Test_newLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping *Mapping
+		header  []string
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			mapping: DefaultMapping(),
+			header:  []string{"", "Name", "Address", "Phone", "Longitude", "Latitude"},
+		},
+		{
+			name:    "without address and phone",
+			mapping: &Mapping{Columns: Columns{ID: "id", Name: "name", Lon: "x", Lat: "y"}},
+			header:  []string{"id", "name", "x", "y"},
+		},
+		{
+			name:    "misspelt column",
+			mapping: DefaultMapping(),
+			header:  []string{"", "Name", "Address", "Phone", "Longtitude", "Latitude"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate column",
+			mapping: DefaultMapping(),
+			header:  []string{"", "Name", "Name", "Address", "Phone", "Longitude", "Latitude"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid coordinate order",
+			mapping: &Mapping{Columns: Columns{ID: "id", Name: "name", Coordinates: "geo", Order: "north,east"}},
+			header:  []string{"id", "name", "geo"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newLayout(tt.mapping, tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
 	type args struct {
 		filename string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		want    []*entity.Restaurant
-		wantErr bool
+		name      string
+		args      args
+		want      []*entity.Restaurant
+		wantLines []int
+		wantErrAt int
 	}{
 		{
 			name: "test1",
@@ -103,20 +189,247 @@ func TestParseCSV(t *testing.T) {
 					}{Lon: 37.6696475969381, Lat: 55.7355114718314},
 				},
 			},
-			wantErr: false,
+			wantLines: []int{2, 3, 4},
+		},
+		{
+			name: "invalid row",
+			args: args{
+				filename: writeFile(t, "\tName\tAddress\tPhone\tLongitude\tLatitude\n"+
+					"0\tA\tB\tC\tnot a number\t55.7\n"+
+					sampleLine),
+			},
+			want: []*entity.Restaurant{
+				nil,
+				{
+					ID:      "9",
+					Name:    "ShKOLA 735",
+					Address: "gorod Moskva, Aviamotornaja ulitsa, dom 51",
+					Phone:   "(495) 273-21-06",
+					Location: struct {
+						Lon float64 `json:"lon"`
+						Lat float64 `json:"lat"`
+					}{Lon: 37.72098869657803, Lat: 55.746325696672486},
+				},
+			},
+			wantLines: []int{2, 3},
+			wantErrAt: 2,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			parser := New()
-			got, err := parser.ParseCSV(tt.args.filename)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("ParseCSV() error = %v, wantErr %v", err, tt.wantErr)
-				return
+			parser := New(4, 2)
+			records, size, err := parser.Import(context.Background(), tt.args.filename)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			var got []*entity.Restaurant
+			var lines []int
+			var offset int64
+			for rec := range records {
+				if (rec.Err != nil) != (rec.Line == tt.wantErrAt) {
+					t.Errorf("Import() line %d error = %v", rec.Line, rec.Err)
+				}
+				got = append(got, rec.Place)
+				lines = append(lines, rec.Line)
+				offset = rec.Offset
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ParseCSV() got = %v, want %v", got, tt.want)
+				t.Errorf("Import() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("Import() lines = %v, want %v", lines, tt.wantLines)
+			}
+			if offset != size {
+				t.Errorf("Import() last offset = %d, want the file size %d", offset, size)
+			}
+		})
+	}
+}
+
+func TestImport_InvalidRows(t *testing.T) {
+	content := "\tName\tAddress\tPhone\tLongitude\tLatitude\n" +
+		"0\tA\tB\tC\t37.6\n" +
+		"1\tA \"quoted\" name\tB\tC\t37.6\t55.7\n" +
+		"2\tA\tB\tC\tnot a number\t55.7\r\n" +
+		sampleLine
+	type row struct {
+		line  int
+		raw   string
+		valid bool
+	}
+	want := []row{
+		{line: 2, raw: "0\tA\tB\tC\t37.6"},
+		{line: 3, raw: "1\tA \"quoted\" name\tB\tC\t37.6\t55.7"},
+		{line: 4, raw: "2\tA\tB\tC\tnot a number\t55.7"},
+		{line: 5, raw: strings.TrimSuffix(sampleLine, "\n"), valid: true},
+	}
+	records, _, err := New(2, 1).Import(context.Background(), writeFile(t, content))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	var got []row
+	for rec := range records {
+		got = append(got, row{line: rec.Line, raw: rec.Raw, valid: rec.Err == nil})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Import() got = %+v, want %+v", got, want)
+	}
+}
+
+func TestImport_Mapping(t *testing.T) {
+	filename := writeFile(t, "name;lat;lon;id;cuisine\n"+
+		"Cafe;55.7;37.6;7;georgian\n")
+	mapping := "delimiter: \";\"\n" +
+		"columns:\n" +
+		"  id: id\n" +
+		"  name: name\n" +
+		"  lon: lon\n" +
+		"  lat: lat\n"
+	path := filepath.Join(filepath.Dir(filename), "data"+MappingSuffix)
+	if err := os.WriteFile(path, []byte(mapping), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	records, _, err := New(1, 1).Import(context.Background(), filename)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	want := &entity.Restaurant{
+		ID:   "7",
+		Name: "Cafe",
+		Location: struct {
+			Lon float64 `json:"lon"`
+			Lat float64 `json:"lat"`
+		}{Lon: 37.6, Lat: 55.7},
+		Extra: map[string]string{"cuisine": "georgian"},
+	}
+	var got []*entity.Restaurant
+	for rec := range records {
+		if rec.Err != nil {
+			t.Fatalf("Import() line %d error = %v", rec.Line, rec.Err)
+		}
+		got = append(got, rec.Place)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Import() got = %v, want %v", got, want)
+	}
+}
+
+func TestImport_Encoding(t *testing.T) {
+	header := "\tName\tAddress\tPhone\tLongitude\tLatitude\n"
+	row := "1\tКафе «Академия»\tгород Москва, Абельмановская улица, дом 6\t(495) 662-30-10\t37.66\t55.73\n"
+	encode := func(enc *charmap.Charmap, s string) string {
+		encoded, err := enc.NewEncoder().String(s)
+		if err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		return encoded
+	}
+	tests := []struct {
+		name     string
+		content  string
+		encoding string
+		wantName string
+		wantErr  bool
+	}{
+		{
+			name:     "UTF-8",
+			content:  header + row,
+			wantName: "Кафе «Академия»",
+		},
+		{
+			name:     "UTF-8 with BOM",
+			content:  "\xEF\xBB\xBF" + header + row,
+			wantName: "Кафе «Академия»",
+		},
+		{
+			name:     "Windows-1251",
+			content:  encode(charmap.Windows1251, header+row),
+			wantName: "Кафе «Академия»",
+		},
+		{
+			name:     "KOI8-R",
+			content:  encode(charmap.KOI8R, header+strings.ReplaceAll(row, "«Академия»", "Академия")),
+			wantName: "Кафе Академия",
+		},
+		{
+			// Capitals in KOI8-R fall where lowercase letters are in Windows-1251.
+			name:     "override",
+			content:  encode(charmap.KOI8R, header+"1\tКАФЕ\tМОСКВА\t-\t37.66\t55.73\n"),
+			encoding: "koi8-r",
+			wantName: "КАФЕ",
+		},
+		{
+			name:    "invalid UTF-8 left",
+			content: header + strings.Repeat(row, 20) + "2\tKafe \xff\tB\tC\t37.6\t55.7\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeFile(t, tt.content)
+			if tt.encoding != "" {
+				mapping := "encoding: " + tt.encoding + "\n" +
+					"columns:\n  name: Name\n  address: Address\n  phone: Phone\n  lon: Longitude\n  lat: Latitude\n"
+				path := filepath.Join(filepath.Dir(filename), "data"+MappingSuffix)
+				if err := os.WriteFile(path, []byte(mapping), 0o644); err != nil {
+					t.Fatalf("failed to write %s: %v", path, err)
+				}
+			}
+			records, size, err := New(2, 1).Import(context.Background(), filename)
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			var last entity.Record
+			var invalid int
+			for rec := range records {
+				if rec.Err != nil {
+					invalid++
+				}
+				last = rec
+			}
+			if (invalid > 0) != tt.wantErr {
+				t.Fatalf("Import() %d invalid rows, wantErr %v: %v", invalid, tt.wantErr, last.Err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if last.Place.Name != tt.wantName {
+				t.Errorf("Import() name = %q, want %q", last.Place.Name, tt.wantName)
+			}
+			if last.Offset != size {
+				t.Errorf("Import() last offset = %d, want the file size %d", last.Offset, size)
 			}
 		})
 	}
 }
+
+func TestImport_Cancel(t *testing.T) {
+	var data strings.Builder
+	data.WriteString("\tName\tAddress\tPhone\tLongitude\tLatitude\n")
+	for range 10000 {
+		data.WriteString(sampleLine)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	records, _, err := New(4, 16).Import(ctx, writeFile(t, data.String()))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	<-records
+	cancel()
+	n := 0
+	for range records {
+		n++
+	}
+	if n >= 9999 {
+		t.Errorf("Import() sent %d more records after cancellation", n)
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return name
+}
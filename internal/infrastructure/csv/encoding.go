@@ -0,0 +1,142 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// EncodingAuto tells the encoding of a data set from its beginning.
+const EncodingAuto = "auto"
+
+// detectSize is how much of the beginning of a file its encoding is told from.
+const detectSize = 64 << 10
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// source is a data set converted to UTF-8.
+type source struct {
+	io.Reader
+	// encoding is the name of the encoding the file is in.
+	encoding string
+	// bom is the size of the byte order mark skipped at the beginning of a UTF-8 file.
+	bom int64
+	// file counts the bytes read from a file that is converted, as its offsets differ from those of the text.
+	file *countingReader
+}
+
+// offset returns the offset in the file of the given offset in the text read from it.
+// For a converted file it is how much of the file has been read, which runs ahead of the text by the buffers.
+func (s *source) offset(text int64) int64 {
+	if s.file != nil {
+		return s.file.n
+	}
+	return text + s.bom
+}
+
+// newSource converts the file to UTF-8 from the named encoding, any label of the WHATWG Encoding Standard
+// such as "windows-1251" or "koi8-r". An empty name or "auto" tells the encoding from the beginning of the file.
+func newSource(file io.Reader, name string) (*source, error) {
+	counter := &countingReader{r: file}
+	br := bufio.NewReaderSize(counter, detectSize)
+	head, err := br.Peek(detectSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if name == "" || strings.EqualFold(name, EncodingAuto) {
+		name = detectEncoding(head)
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	canonical, _ := htmlindex.Name(enc)
+
+	if canonical == "utf-8" {
+		s := &source{Reader: br, encoding: canonical}
+		if bytes.HasPrefix(head, utf8BOM) {
+			if _, err := br.Discard(len(utf8BOM)); err != nil {
+				return nil, err
+			}
+			s.bom = int64(len(utf8BOM))
+		}
+		return s, nil
+	}
+	// A byte order mark overrides the encoding, as it does in browsers.
+	decoder := unicode.BOMOverride(enc.NewDecoder())
+	return &source{Reader: transform.NewReader(br, decoder), encoding: canonical, file: counter}, nil
+}
+
+// detectEncoding tells the encoding of a text from its beginning: a byte order mark, valid UTF-8,
+// or else one of the Cyrillic single-byte encodings, told apart by where most letters fall.
+// Most letters of a Russian text are lowercase, which is 0xE0-0xFF in Windows-1251 and 0xC0-0xDF in KOI8-R.
+func detectEncoding(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return "utf-8"
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return "utf-16le"
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return "utf-16be"
+	case mostlyUTF8(head):
+		return "utf-8"
+	}
+	var cp1251, koi8r int
+	for _, b := range head {
+		switch {
+		case b >= 0xE0:
+			cp1251++
+		case b >= 0xC0:
+			koi8r++
+		}
+	}
+	if koi8r > cp1251 {
+		return "koi8-r"
+	}
+	return "windows-1251"
+}
+
+// mostlyUTF8 tells whether a text is UTF-8 with at most a few broken sequences, which are left
+// for the rows holding them to be rejected. A sequence cut at the end of the text is not counted.
+func mostlyUTF8(text []byte) bool {
+	var multibyte, invalid int
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		switch {
+		case r == utf8.RuneError && size == 1 && !utf8.FullRune(text):
+			text = nil
+			continue
+		case r == utf8.RuneError && size == 1:
+			invalid++
+		case size > 1:
+			multibyte++
+		}
+		text = text[size:]
+	}
+	return invalid == 0 || multibyte > 10*invalid
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// validUTF8 tells whether a field holds valid UTF-8, without the replacement characters
+// a conversion puts in place of bytes the encoding has no character for.
+func validUTF8(field string) bool {
+	return utf8.ValidString(field) && !strings.ContainsRune(field, utf8.RuneError)
+}
@@ -0,0 +1,232 @@
+package csv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ilyakaznacheev/cleanenv"
+	"nearestPlaces/internal/entity"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MappingSuffix ends the name of the mapping file kept next to a data set:
+// the mapping of datasets/data.csv is datasets/data.mapping.yaml.
+const MappingSuffix = ".mapping.yaml"
+
+// Mapping describes the layout of a data set: the delimiter of its fields
+// and the header names of the columns holding the fields of a place.
+type Mapping struct {
+	Delimiter string `yaml:"delimiter" env-default:"\t"`
+	// Encoding is the encoding of the file, such as "windows-1251"; it is told from the file when empty or "auto".
+	Encoding string  `yaml:"encoding"`
+	Columns  Columns `yaml:"columns"`
+}
+
+// Columns name the header columns of the place fields. Address and Phone may be left empty
+// when the data set has no such columns. An empty ID names the column with an empty header.
+type Columns struct {
+	ID      string `yaml:"id"`
+	Name    string `yaml:"name"`
+	Address string `yaml:"address"`
+	Phone   string `yaml:"phone"`
+	Lon     string `yaml:"lon"`
+	Lat     string `yaml:"lat"`
+	// Coordinates is a single column holding both coordinates, used instead of Lon and Lat.
+	Coordinates string `yaml:"coordinates"`
+	// Order is the order of the coordinates in the Coordinates column: "lat,lon" or "lon,lat".
+	Order string `yaml:"order" env-default:"lat,lon"`
+}
+
+// DefaultMapping is the layout of the data sets exported from the Moscow open data portal.
+func DefaultMapping() *Mapping {
+	return &Mapping{
+		Delimiter: "\t",
+		Columns: Columns{
+			Name:    "Name",
+			Address: "Address",
+			Phone:   "Phone",
+			Lon:     "Longitude",
+			Lat:     "Latitude",
+			Order:   "lat,lon",
+		},
+	}
+}
+
+// MappingPath returns the path of the mapping kept next to the data set.
+func MappingPath(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + MappingSuffix
+}
+
+// ReadMapping reads the mapping kept next to the data set. Data sets without one get the default mapping.
+func ReadMapping(filename string) (*Mapping, error) {
+	path := MappingPath(filename)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return DefaultMapping(), nil
+	}
+	var m Mapping
+	if err := cleanenv.ReadConfig(path, &m); err != nil {
+		return nil, fmt.Errorf("failed to read mapping %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// comma returns the delimiter as the single character the CSV reader takes.
+func (m *Mapping) comma() (rune, error) {
+	r, size := utf8.DecodeRuneInString(m.Delimiter)
+	if size == 0 || size != len(m.Delimiter) || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter %q", m.Delimiter)
+	}
+	return r, nil
+}
+
+// layout holds the positions of the place fields in the rows of a data set, found by the names in its header.
+// The positions of the columns not mapped to a field are kept in extra.
+type layout struct {
+	header      []string
+	width       int
+	id          int
+	name        int
+	address     int
+	phone       int
+	lon         int
+	lat         int
+	coordinates int
+	lonFirst    bool
+	extra       map[int]string
+}
+
+// newLayout finds the mapped columns in the header. A column the mapping names must be there,
+// so that a misspelt name fails the load instead of leaving a field empty.
+func newLayout(m *Mapping, header []string) (*layout, error) {
+	cols := m.Columns
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if _, ok := positions[h]; !ok {
+			positions[h] = i
+		} else if h != "" {
+			return nil, fmt.Errorf("duplicate column %q in header", h)
+		}
+	}
+	used := make(map[int]bool)
+	find := func(field, column string, optional bool) (int, error) {
+		if column == "" && optional {
+			return -1, nil
+		}
+		i, ok := positions[column]
+		if !ok {
+			return -1, fmt.Errorf("column %q for %s not found in header", column, field)
+		}
+		used[i] = true
+		return i, nil
+	}
+
+	l := &layout{header: header, width: len(header), lon: -1, lat: -1, coordinates: -1, extra: make(map[int]string)}
+	var err error
+	if l.id, err = find("id", cols.ID, false); err != nil {
+		return nil, err
+	}
+	if l.name, err = find("name", cols.Name, false); err != nil {
+		return nil, err
+	}
+	if l.address, err = find("address", cols.Address, true); err != nil {
+		return nil, err
+	}
+	if l.phone, err = find("phone", cols.Phone, true); err != nil {
+		return nil, err
+	}
+	if cols.Coordinates != "" {
+		if l.coordinates, err = find("coordinates", cols.Coordinates, false); err != nil {
+			return nil, err
+		}
+		switch strings.ReplaceAll(cols.Order, " ", "") {
+		case "lat,lon":
+		case "lon,lat":
+			l.lonFirst = true
+		default:
+			return nil, fmt.Errorf("invalid coordinate order %q: must be \"lat,lon\" or \"lon,lat\"", cols.Order)
+		}
+	} else {
+		if l.lon, err = find("lon", cols.Lon, false); err != nil {
+			return nil, err
+		}
+		if l.lat, err = find("lat", cols.Lat, false); err != nil {
+			return nil, err
+		}
+	}
+	for i, h := range header {
+		if h = strings.TrimSpace(h); !used[i] && h != "" {
+			l.extra[i] = h
+		}
+	}
+	return l, nil
+}
+
+// field returns the value at position i, or an empty string for a column that is not mapped.
+func field(line []string, i int) string {
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSpace(line[i])
+}
+
+// coords returns the longitude and latitude of the row.
+func (l *layout) coords(line []string) (string, string, error) {
+	if l.coordinates < 0 {
+		return field(line, l.lon), field(line, l.lat), nil
+	}
+	value := field(line, l.coordinates)
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	})
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid coordinates: %s", value)
+	}
+	if l.lonFirst {
+		return parts[0], parts[1], nil
+	}
+	return parts[1], parts[0], nil
+}
+
+func (l *layout) parse(line []string) (*entity.Restaurant, error) {
+	if len(line) != l.width {
+		return nil, fmt.Errorf("wrong number of fields: %d instead of %d", len(line), l.width)
+	}
+	for i, f := range line {
+		if !validUTF8(f) {
+			return nil, fmt.Errorf("invalid UTF-8 in column %q", l.header[i])
+		}
+	}
+	lonText, latText, err := l.coords(line)
+	if err != nil {
+		return nil, err
+	}
+	lon, err := strconv.ParseFloat(lonText, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Longitude: %s", lonText)
+	}
+	lat, err := strconv.ParseFloat(latText, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Latitude: %s", latText)
+	}
+	place := &entity.Restaurant{
+		ID:      field(line, l.id),
+		Name:    field(line, l.name),
+		Address: field(line, l.address),
+		Phone:   field(line, l.phone),
+	}
+	place.Location.Lon, place.Location.Lat = lon, lat
+	for i, name := range l.extra {
+		if v := strings.TrimSpace(line[i]); v != "" {
+			if place.Extra == nil {
+				place.Extra = make(map[string]string, len(l.extra))
+			}
+			place.Extra[name] = v
+		}
+	}
+	return place, nil
+}
@@ -0,0 +1,65 @@
+package geoip
+
+import (
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"nearestPlaces/internal/entity"
+	"net/netip"
+)
+
+// Reader looks IP addresses up in a MaxMind-format City database such as GeoLite2-City or DB-IP City Lite.
+type Reader struct {
+	db *maxminddb.Reader
+}
+
+func Open(path string) (*Reader, error) {
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening geoip database %s: %w", path, err)
+	}
+	return &Reader{db: db}, nil
+}
+
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
+
+// record is the part of a City database record the lookup needs.
+type record struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Location struct {
+		// AccuracyRadius is in kilometres.
+		AccuracyRadius uint16   `maxminddb:"accuracy_radius"`
+		Latitude       *float64 `maxminddb:"latitude"`
+		Longitude      *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// Lookup returns the approximate location of the address. Addresses the database
+// does not know or knows no coordinates of are reported as entity.ErrNotFound.
+func (r *Reader) Lookup(ip netip.Addr) (*entity.Origin, error) {
+	var rec record
+	_, ok, err := r.db.LookupNetwork(ip.Unmap().AsSlice(), &rec)
+	if err != nil {
+		return nil, fmt.Errorf("error while looking up %s: %w", ip, err)
+	}
+	if !ok || rec.Location.Latitude == nil || rec.Location.Longitude == nil {
+		return nil, fmt.Errorf("location of %s: %w", ip, entity.ErrNotFound)
+	}
+	return &entity.Origin{
+		Point: entity.Point{
+			Lat: *rec.Location.Latitude,
+			Lon: *rec.Location.Longitude,
+		},
+		Source:         entity.OriginGeoIP,
+		Approximate:    true,
+		AccuracyRadius: float64(rec.Location.AccuracyRadius) * 1000,
+		City:           rec.City.Names["en"],
+		Country:        rec.Country.ISOCode,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package geoip
+
+import (
+	"errors"
+	"nearestPlaces/internal/entity"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// testdata/test-city.mmdb is an IPv4 City database holding a single network, 8.8.8.0/24,
+// located in Mountain View, US, with an accuracy radius of 1000 km.
+func TestReader_Lookup(t *testing.T) {
+	r, err := Open("testdata/test-city.mmdb")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+
+	mountainView := &entity.Origin{
+		Point:          entity.Point{Lat: 37.386, Lon: -122.0838},
+		Source:         entity.OriginGeoIP,
+		Approximate:    true,
+		AccuracyRadius: 1000 * 1000,
+		City:           "Mountain View",
+		Country:        "US",
+	}
+	tests := []struct {
+		name    string
+		ip      string
+		want    *entity.Origin
+		wantErr error
+	}{
+		{name: "known", ip: "8.8.8.8", want: mountainView},
+		{name: "mapped", ip: "::ffff:8.8.8.8", want: mountainView},
+		{name: "unknown", ip: "9.9.9.9", wantErr: entity.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Lookup(netip.MustParseAddr(tt.ip))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lookup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen_missing(t *testing.T) {
+	if _, err := Open("testdata/missing.mmdb"); err == nil {
+		t.Error("Open() of a missing file error = nil, want error")
+	}
+}
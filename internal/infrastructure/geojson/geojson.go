@@ -0,0 +1,209 @@
+package geojson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/infrastructure/importer"
+	"os"
+)
+
+type Importer struct {
+	buffer int
+}
+
+// New creates an importer that keeps up to buffer places waiting for the consumer.
+func New(buffer int) *Importer {
+	return &Importer{
+		buffer: max(buffer, 1),
+	}
+}
+
+// feature is a GeoJSON feature; its geometry has to be a point.
+type feature struct {
+	Type     string          `json:"type"`
+	ID       json.RawMessage `json:"id"`
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+// Import reads a GeoJSON FeatureCollection feature by feature, so that the collection is never held
+// in memory as a whole. The id, name, address and phone properties fill the fields of a place,
+// the other properties become extra attributes; the feature id takes precedence over the id property.
+// The channel is closed after the last feature, after the file fails to be read, or when ctx is done.
+func (i *Importer) Import(ctx context.Context, filename string) (<-chan entity.Record, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat file %s: %w", filename, err)
+	}
+
+	out := make(chan entity.Record, i.buffer)
+	go func() {
+		defer file.Close()
+		defer close(out)
+		src := &lineReader{r: file}
+		s := &stream{ctx: ctx, src: src, dec: json.NewDecoder(src), out: out}
+		if err := s.collection(); err != nil {
+			offset := s.dec.InputOffset()
+			s.send(entity.Record{Line: src.lineAt(offset), Offset: offset, Err: fmt.Errorf("%w: %w", entity.ErrSourceBroken, err)})
+		}
+	}()
+	return out, info.Size(), nil
+}
+
+// errStopped ends the stream when ctx is done.
+var errStopped = errors.New("stopped")
+
+type stream struct {
+	ctx context.Context
+	src *lineReader
+	dec *json.Decoder
+	out chan<- entity.Record
+}
+
+func (s *stream) send(rec entity.Record) bool {
+	select {
+	case s.out <- rec:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// collection walks the members of the top-level object, streaming the features
+// and skipping the members it has no use for.
+func (s *stream) collection() error {
+	if err := s.delim('{'); err != nil {
+		return err
+	}
+	for s.dec.More() {
+		key, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "type":
+			var typ string
+			if err := s.dec.Decode(&typ); err != nil {
+				return err
+			}
+			if typ != "FeatureCollection" {
+				return fmt.Errorf("got a %s instead of a FeatureCollection", typ)
+			}
+		case "features":
+			if err := s.features(); err != nil {
+				if errors.Is(err, errStopped) {
+					return nil
+				}
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := s.dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	return s.delim('}')
+}
+
+func (s *stream) features() error {
+	if err := s.delim('['); err != nil {
+		return err
+	}
+	for s.dec.More() {
+		var raw json.RawMessage
+		if err := s.dec.Decode(&raw); err != nil {
+			return err
+		}
+		offset := s.dec.InputOffset()
+		rec := entity.Record{Line: s.src.lineAt(offset - int64(len(raw))), Offset: offset, Raw: compact(raw)}
+		rec.Place, rec.Err = parse(raw)
+		if !s.send(rec) {
+			return errStopped
+		}
+	}
+	return s.delim(']')
+}
+
+func (s *stream) delim(want json.Delim) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+func parse(raw json.RawMessage) (*entity.Restaurant, error) {
+	var f feature
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("invalid feature: %w", err)
+	}
+	if f.Type != "Feature" {
+		return nil, fmt.Errorf("got a %s instead of a Feature", f.Type)
+	}
+	if f.Geometry == nil {
+		return nil, errors.New("feature has no geometry")
+	}
+	if f.Geometry.Type != "Point" {
+		return nil, fmt.Errorf("unsupported geometry %s: only points are loaded", f.Geometry.Type)
+	}
+	var coords []float64
+	if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil || len(coords) < 2 {
+		return nil, fmt.Errorf("invalid point coordinates: %s", importer.Text(f.Geometry.Coordinates))
+	}
+	place := importer.PlaceFromAttributes(f.Properties)
+	if id := importer.Text(f.ID); id != "" {
+		place.ID = id
+	}
+	place.Location.Lon, place.Location.Lat = coords[0], coords[1]
+	return place, nil
+}
+
+func compact(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// lineReader keeps the bytes read from the file that have not been counted yet,
+// so that the line of an offset can be told without reading the file twice.
+type lineReader struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+	line int
+}
+
+func (lr *lineReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.buf = append(lr.buf, p[:n]...)
+	return n, err
+}
+
+// lineAt returns the line of the byte at the offset, counting from 1.
+// Offsets must not go back.
+func (lr *lineReader) lineAt(offset int64) int {
+	n := min(max(int(offset-lr.base), 0), len(lr.buf))
+	lr.line += bytes.Count(lr.buf[:n], []byte{'\n'})
+	lr.buf = lr.buf[n:]
+	lr.base += int64(n)
+	return lr.line + 1
+}
@@ -0,0 +1,61 @@
+package geojson
+
+import (
+	"context"
+	"nearestPlaces/internal/entity"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	content := `{
+  "type": "FeatureCollection",
+  "name": "cafes",
+  "features": [
+    {"type": "Feature", "id": 7, "geometry": {"type": "Point", "coordinates": [37.6, 55.7]},
+     "properties": {"name": "Cafe", "address": "ulitsa Grekova, dom 3", "seats": 40, "wifi": true}},
+    {"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[37.6, 55.7], [37.7, 55.7], [37.6, 55.8], [37.6, 55.7]]]},
+     "properties": {"id": "8", "name": "Park"}},
+    {"type": "Feature", "geometry": {"type": "Point", "coordinates": [37.5, 55.6]}, "properties": {"id": "9", "name": "Bar", "phone": null}}
+  ]
+}
+`
+	type row struct {
+		line  int
+		place *entity.Restaurant
+		valid bool
+	}
+	place := func(id, name, address string, lon, lat float64, extra map[string]string) *entity.Restaurant {
+		p := &entity.Restaurant{ID: id, Name: name, Address: address, Extra: extra}
+		p.Location.Lon, p.Location.Lat = lon, lat
+		return p
+	}
+	want := []row{
+		{line: 5, place: place("7", "Cafe", "ulitsa Grekova, dom 3", 37.6, 55.7, map[string]string{"seats": "40", "wifi": "true"}), valid: true},
+		{line: 7},
+		{line: 9, place: place("9", "Bar", "", 37.5, 55.6, nil), valid: true},
+	}
+
+	name := filepath.Join(t.TempDir(), "data.geojson")
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	records, size, err := New(1).Import(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	var got []row
+	var offset int64
+	for rec := range records {
+		got = append(got, row{line: rec.Line, place: rec.Place, valid: rec.Err == nil})
+		offset = rec.Offset
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Import() got = %+v, want %+v", got, want)
+	}
+	if offset > size {
+		t.Errorf("Import() last offset = %d, beyond the file size %d", offset, size)
+	}
+}
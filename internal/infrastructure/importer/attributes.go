@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"nearestPlaces/internal/entity"
+	"slices"
+	"strconv"
+)
+
+// PlaceFromAttributes builds a place from the attributes of a JSON object. The id, name, address
+// and phone attributes fill the fields of the place, the others become its extra attributes.
+// Attributes listed in skip are left out, and the location is left to the caller.
+func PlaceFromAttributes(attrs map[string]json.RawMessage, skip ...string) *entity.Restaurant {
+	place := &entity.Restaurant{
+		ID:      Text(attrs["id"]),
+		Name:    Text(attrs["name"]),
+		Address: Text(attrs["address"]),
+		Phone:   Text(attrs["phone"]),
+	}
+	for key, value := range attrs {
+		switch key {
+		case "id", "name", "address", "phone":
+			continue
+		}
+		if slices.Contains(skip, key) {
+			continue
+		}
+		if text := Text(value); text != "" {
+			if place.Extra == nil {
+				place.Extra = make(map[string]string)
+			}
+			place.Extra[key] = text
+		}
+	}
+	return place
+}
+
+// Text returns a JSON value as text: strings unquoted, null as an empty string,
+// numbers, booleans, objects and arrays as they are written.
+func Text(value json.RawMessage) string {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	if value[0] == '"' {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			return s
+		}
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, value); err != nil {
+		return string(value)
+	}
+	return compact.String()
+}
+
+// Float reads a JSON number, also accepting one written as a string.
+func Float(value json.RawMessage) (float64, bool) {
+	f, err := strconv.ParseFloat(Text(value), 64)
+	return f, err == nil
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"nearestPlaces/internal/entity"
+	"path/filepath"
+	"strings"
+)
+
+// Data set formats.
+const (
+	FormatCSV     = "csv"
+	FormatGeoJSON = "geojson"
+	FormatNDJSON  = "ndjson"
+	FormatOSM     = "osm"
+)
+
+// extensions tell the format of a data set from the extension of its file.
+var extensions = map[string]string{
+	".csv":     FormatCSV,
+	".tsv":     FormatCSV,
+	".txt":     FormatCSV,
+	".geojson": FormatGeoJSON,
+	".ndjson":  FormatNDJSON,
+	".jsonl":   FormatNDJSON,
+	".osm":     FormatOSM,
+	".xml":     FormatOSM,
+}
+
+// Importer streams the places of a data set in a single format.
+type Importer interface {
+	Import(ctx context.Context, filename string) (<-chan entity.Record, int64, error)
+}
+
+// Registry hands a data set to the importer of its format.
+type Registry struct {
+	importers map[string]Importer
+}
+
+// New creates a registry of importers keyed by format.
+func New(importers map[string]Importer) *Registry {
+	return &Registry{
+		importers: importers,
+	}
+}
+
+// Import streams the places of the file with the importer of the format.
+// An empty format is told from the file extension.
+func (r *Registry) Import(ctx context.Context, filename, format string) (<-chan entity.Record, int64, error) {
+	format, err := r.Format(filename, format)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r.importers[format].Import(ctx, filename)
+}
+
+// Format checks that the format has an importer, telling an empty one from the file extension.
+func (r *Registry) Format(filename, format string) (string, error) {
+	if format == "" {
+		var err error
+		if format, err = FormatOf(filename); err != nil {
+			return "", err
+		}
+	}
+	if _, ok := r.importers[format]; !ok {
+		return "", fmt.Errorf("%w: unknown data format %q", entity.ErrInvalidArgument, format)
+	}
+	return format, nil
+}
+
+// FormatOf tells the format of a data set from the extension of its file.
+func FormatOf(filename string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	format, ok := extensions[ext]
+	if !ok {
+		return "", fmt.Errorf("%w: cannot tell the data format of %s from its extension", entity.ErrInvalidArgument, filepath.Base(filename))
+	}
+	return format, nil
+}
@@ -0,0 +1,122 @@
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/infrastructure/importer"
+	"os"
+)
+
+type Importer struct {
+	buffer int
+}
+
+// New creates an importer that keeps up to buffer places waiting for the consumer.
+func New(buffer int) *Importer {
+	return &Importer{
+		buffer: max(buffer, 1),
+	}
+}
+
+// Import reads a file of JSON objects, one per line, shaped like the places the API returns:
+//
+//	{"id": "7", "name": "Cafe", "address": "...", "phone": "...", "location": {"lon": 37.6, "lat": 55.7}}
+//
+// The coordinates may also be given as lat and lon attributes of the object. Attributes other than
+// the fields of a place, as well as the entries of an extra object, become extra attributes.
+// Blank lines are skipped. The channel is closed after the last line, after the file fails to be read,
+// or when ctx is done.
+func (i *Importer) Import(ctx context.Context, filename string) (<-chan entity.Record, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat file %s: %w", filename, err)
+	}
+
+	out := make(chan entity.Record, i.buffer)
+	go func() {
+		defer file.Close()
+		defer close(out)
+		read(ctx, file, out)
+	}()
+	return out, info.Size(), nil
+}
+
+func read(ctx context.Context, file io.Reader, out chan<- entity.Record) {
+	r := bufio.NewReader(file)
+	var offset int64
+	for line := 1; ; line++ {
+		text, err := r.ReadBytes('\n')
+		offset += int64(len(text))
+		if err != nil && !errors.Is(err, io.EOF) {
+			rec := entity.Record{Line: line, Offset: offset, Err: fmt.Errorf("%w: %w", entity.ErrSourceBroken, err)}
+			select {
+			case out <- rec:
+			case <-ctx.Done():
+			}
+			return
+		}
+		if text = bytes.TrimSpace(text); len(text) > 0 {
+			rec := entity.Record{Line: line, Offset: offset, Raw: string(text)}
+			rec.Place, rec.Err = parse(text)
+			select {
+			case out <- rec:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func parse(text []byte) (*entity.Restaurant, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(text, &attrs); err != nil {
+		return nil, fmt.Errorf("invalid JSON object: %w", err)
+	}
+	place := importer.PlaceFromAttributes(attrs, "location", "lat", "lon", "extra")
+
+	coords := attrs
+	if location, ok := attrs["location"]; ok {
+		if err := json.Unmarshal(location, &coords); err != nil {
+			return nil, fmt.Errorf("invalid location: %s", importer.Text(location))
+		}
+	}
+	lon, ok := importer.Float(coords["lon"])
+	if !ok {
+		return nil, fmt.Errorf("invalid Longitude: %s", importer.Text(coords["lon"]))
+	}
+	lat, ok := importer.Float(coords["lat"])
+	if !ok {
+		return nil, fmt.Errorf("invalid Latitude: %s", importer.Text(coords["lat"]))
+	}
+	place.Location.Lon, place.Location.Lat = lon, lat
+
+	if extra, ok := attrs["extra"]; ok {
+		var more map[string]json.RawMessage
+		if err := json.Unmarshal(extra, &more); err != nil {
+			return nil, fmt.Errorf("invalid extra: %s", importer.Text(extra))
+		}
+		for key, value := range more {
+			if text := importer.Text(value); text != "" {
+				if place.Extra == nil {
+					place.Extra = make(map[string]string, len(more))
+				}
+				place.Extra[key] = text
+			}
+		}
+	}
+	return place, nil
+}
@@ -0,0 +1,60 @@
+package ndjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantID    string
+		wantLon   float64
+		wantLat   float64
+		wantExtra map[string]string
+		wantErr   bool
+	}{
+		{
+			name:    "as the API returns it",
+			text:    `{"id":"7","name":"Cafe","address":"","phone":"","location":{"lon":37.6,"lat":55.7},"extra":{"District":"Tverskoy"}}`,
+			wantID:  "7",
+			wantLon: 37.6, wantLat: 55.7,
+			wantExtra: map[string]string{"District": "Tverskoy"},
+		},
+		{
+			name:    "flat coordinates and extra attributes",
+			text:    `{"id":8,"name":"Bar","lat":"55.8","lon":37.5,"cuisine":"georgian","tags":["wifi"]}`,
+			wantID:  "8",
+			wantLon: 37.5, wantLat: 55.8,
+			wantExtra: map[string]string{"cuisine": "georgian", "tags": `["wifi"]`},
+		},
+		{
+			name:    "no coordinates",
+			text:    `{"id":"9","name":"Cafe"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			text:    `["9","Cafe"]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse([]byte(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.ID != tt.wantID || got.Location.Lon != tt.wantLon || got.Location.Lat != tt.wantLat {
+				t.Errorf("parse() got = %+v", got)
+			}
+			if !reflect.DeepEqual(got.Extra, tt.wantExtra) {
+				t.Errorf("parse() extra = %v, want %v", got.Extra, tt.wantExtra)
+			}
+		})
+	}
+}
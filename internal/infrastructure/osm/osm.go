@@ -0,0 +1,273 @@
+package osm
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"nearestPlaces/internal/entity"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// amenities are the values of the amenity tag that make an element a place.
+var amenities = map[string]bool{
+	"restaurant": true,
+	"cafe":       true,
+	"fast_food":  true,
+}
+
+// addressTags make up the address of a place, in the order they are written.
+var addressTags = []string{"addr:city", "addr:street", "addr:housenumber"}
+
+type Importer struct {
+	buffer int
+}
+
+// New creates an importer that keeps up to buffer places waiting for the consumer.
+func New(buffer int) *Importer {
+	return &Importer{
+		buffer: max(buffer, 1),
+	}
+}
+
+type tag struct {
+	K string `xml:"k,attr"`
+	V string `xml:"v,attr"`
+}
+
+// element is a node or a way of an OSM file.
+type element struct {
+	ID   int64   `xml:"id,attr"`
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Refs []struct {
+		Ref int64 `xml:"ref,attr"`
+	} `xml:"nd"`
+	Tags []tag `xml:"tag"`
+}
+
+func (e *element) tag(key string) string {
+	for _, t := range e.Tags {
+		if t.K == key {
+			return t.V
+		}
+	}
+	return ""
+}
+
+func (e *element) isPlace() bool {
+	return amenities[e.tag("amenity")]
+}
+
+// Import reads the restaurants, cafes and fast food places of an OpenStreetMap XML file: nodes and ways
+// tagged amenity=restaurant, cafe or fast_food. A way is placed at the centroid of its nodes.
+// The file is read twice: first to learn which nodes the ways need, so that only their coordinates
+// are kept while the places are streamed on the second pass. Nodes are expected before the ways using them,
+// as in the files OSM exports. The channel is closed after the last place, after the file fails to be read,
+// or when ctx is done.
+func (i *Importer) Import(ctx context.Context, filename string) (<-chan entity.Record, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("failed to stat file %s: %w", filename, err)
+	}
+
+	out := make(chan entity.Record, i.buffer)
+	go func() {
+		defer file.Close()
+		defer close(out)
+		s := &stream{ctx: ctx, out: out}
+		if err := s.run(file); err != nil && !errors.Is(err, errStopped) {
+			s.send(entity.Record{Line: s.line, Offset: s.offset, Err: fmt.Errorf("%w: %w", entity.ErrSourceBroken, err)})
+		}
+	}()
+	return out, info.Size(), nil
+}
+
+// errStopped ends the stream when ctx is done.
+var errStopped = errors.New("stopped")
+
+type stream struct {
+	ctx    context.Context
+	out    chan<- entity.Record
+	line   int
+	offset int64
+	// nodes holds the coordinates of the nodes the ways of places are made of.
+	nodes map[int64]*entity.Point
+}
+
+func (s *stream) send(rec entity.Record) bool {
+	select {
+	case s.out <- rec:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *stream) run(file io.ReadSeeker) error {
+	s.nodes = make(map[int64]*entity.Point)
+	err := s.walk(file, false, func(kind string, e *element) error {
+		if kind == "way" && e.isPlace() {
+			for _, nd := range e.Refs {
+				s.nodes[nd.Ref] = nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.walk(file, true, func(kind string, e *element) error {
+		if kind == "node" {
+			if _, ok := s.nodes[e.ID]; ok {
+				s.nodes[e.ID] = &entity.Point{Lat: e.Lat, Lon: e.Lon}
+			}
+		}
+		if !e.isPlace() {
+			return nil
+		}
+		rec := entity.Record{Line: s.line, Offset: s.offset, Raw: kind + "/" + strconv.FormatInt(e.ID, 10)}
+		rec.Place, rec.Err = s.place(kind, e)
+		if !s.send(rec) {
+			return errStopped
+		}
+		return nil
+	})
+}
+
+// walk decodes the ways of the file one by one, and the nodes as well unless told to skip them.
+// Everything else is skipped. The line of the element and the offset of its end are kept in the stream.
+func (s *stream) walk(r io.Reader, nodes bool, visit func(kind string, e *element) error) error {
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		s.line, _ = d.InputPos()
+		if err := s.ctx.Err(); err != nil {
+			return errStopped
+		}
+		switch start.Name.Local {
+		case "osm":
+			continue
+		case "node", "way":
+			if start.Name.Local == "node" && !nodes {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var e element
+			if err := d.DecodeElement(&e, &start); err != nil {
+				return err
+			}
+			s.offset = d.InputOffset()
+			if err := visit(start.Name.Local, &e); err != nil {
+				return err
+			}
+		default:
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *stream) place(kind string, e *element) (*entity.Restaurant, error) {
+	place := &entity.Restaurant{
+		ID:   kind + "/" + strconv.FormatInt(e.ID, 10),
+		Name: e.tag("name"),
+	}
+	if place.Phone = e.tag("phone"); place.Phone == "" {
+		place.Phone = e.tag("contact:phone")
+	}
+	if place.Address = e.tag("addr:full"); place.Address == "" {
+		var parts []string
+		for _, key := range addressTags {
+			if v := e.tag(key); v != "" {
+				parts = append(parts, v)
+			}
+		}
+		place.Address = strings.Join(parts, ", ")
+	}
+	for _, t := range e.Tags {
+		switch t.K {
+		case "name", "phone", "contact:phone", "addr:full", "addr:city", "addr:street", "addr:housenumber":
+			continue
+		}
+		if place.Extra == nil {
+			place.Extra = make(map[string]string, len(e.Tags))
+		}
+		place.Extra[t.K] = t.V
+	}
+
+	if kind == "node" {
+		place.Location.Lon, place.Location.Lat = e.Lon, e.Lat
+		return place, nil
+	}
+	points := make([]entity.Point, 0, len(e.Refs))
+	for _, nd := range e.Refs {
+		p := s.nodes[nd.Ref]
+		if p == nil {
+			return nil, fmt.Errorf("node %d of the way is missing", nd.Ref)
+		}
+		points = append(points, *p)
+	}
+	if len(points) == 0 {
+		return nil, errors.New("way has no nodes")
+	}
+	c := centroid(points)
+	place.Location.Lon, place.Location.Lat = c.Lon, c.Lat
+	return place, nil
+}
+
+// centroid returns the centroid of the area a closed way encloses, or the mean of the nodes
+// of an open or degenerate way. Ways are small enough for the degrees to be taken as plane coordinates;
+// they are taken relative to the first node to keep the precision of the small areas of buildings.
+func centroid(points []entity.Point) entity.Point {
+	n := len(points)
+	closed := n >= 4 && points[0] == points[n-1]
+	if closed {
+		o := points[0]
+		var area, x, y float64
+		for i := 0; i < n-1; i++ {
+			px, py := points[i].Lon-o.Lon, points[i].Lat-o.Lat
+			qx, qy := points[i+1].Lon-o.Lon, points[i+1].Lat-o.Lat
+			cross := px*qy - qx*py
+			area += cross
+			x += (px + qx) * cross
+			y += (py + qy) * cross
+		}
+		if area != 0 {
+			return entity.Point{Lon: o.Lon + x/(3*area), Lat: o.Lat + y/(3*area)}
+		}
+		n--
+	}
+	var mean entity.Point
+	for _, p := range points[:n] {
+		mean.Lon += p.Lon
+		mean.Lat += p.Lat
+	}
+	mean.Lon /= float64(n)
+	mean.Lat /= float64(n)
+	return mean
+}
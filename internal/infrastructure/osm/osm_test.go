@@ -0,0 +1,124 @@
+package osm
+
+import (
+	"context"
+	"math"
+	"nearestPlaces/internal/entity"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const sample = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6">
+  <bounds minlat="55.7" minlon="37.6" maxlat="55.8" maxlon="37.7"/>
+  <node id="1" lat="55.70" lon="37.60"/>
+  <node id="2" lat="55.70" lon="37.62"/>
+  <node id="3" lat="55.72" lon="37.62"/>
+  <node id="4" lat="55.72" lon="37.60"/>
+  <node id="5" lat="55.75" lon="37.61">
+    <tag k="amenity" v="cafe"/>
+    <tag k="name" v="Kofeinja"/>
+    <tag k="addr:city" v="Moskva"/>
+    <tag k="addr:street" v="ulitsa Grekova"/>
+    <tag k="addr:housenumber" v="3"/>
+    <tag k="contact:phone" v="+7 495 123-45-67"/>
+  </node>
+  <node id="6" lat="55.76" lon="37.63">
+    <tag k="amenity" v="bench"/>
+  </node>
+  <way id="10">
+    <nd ref="1"/><nd ref="2"/><nd ref="3"/><nd ref="4"/><nd ref="1"/>
+    <tag k="amenity" v="restaurant"/>
+    <tag k="name" v="Stolovaja"/>
+    <tag k="cuisine" v="russian"/>
+  </way>
+  <way id="11">
+    <nd ref="1"/><nd ref="99"/>
+    <tag k="amenity" v="fast_food"/>
+  </way>
+  <relation id="20">
+    <member type="way" ref="10" role="outer"/>
+    <tag k="amenity" v="restaurant"/>
+  </relation>
+</osm>
+`
+
+func TestImport(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data.osm")
+	if err := os.WriteFile(name, []byte(sample), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	records, _, err := New(1).Import(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	var got []entity.Record
+	for rec := range records {
+		got = append(got, rec)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Import() got %d records, want 3: %+v", len(got), got)
+	}
+
+	node := got[0]
+	if node.Err != nil || node.Line != 8 || node.Raw != "node/5" {
+		t.Fatalf("Import() node = %+v", node)
+	}
+	wantNode := &entity.Restaurant{
+		ID:      "node/5",
+		Name:    "Kofeinja",
+		Address: "Moskva, ulitsa Grekova, 3",
+		Phone:   "+7 495 123-45-67",
+		Extra:   map[string]string{"amenity": "cafe"},
+	}
+	wantNode.Location.Lon, wantNode.Location.Lat = 37.61, 55.75
+	if !reflect.DeepEqual(node.Place, wantNode) {
+		t.Errorf("Import() node place = %+v, want %+v", node.Place, wantNode)
+	}
+
+	way := got[1]
+	if way.Err != nil || way.Place.ID != "way/10" || way.Place.Extra["cuisine"] != "russian" {
+		t.Fatalf("Import() way = %+v", way)
+	}
+	if math.Abs(way.Place.Location.Lon-37.61) > 1e-9 || math.Abs(way.Place.Location.Lat-55.71) > 1e-9 {
+		t.Errorf("Import() way location = %+v, want the centre of the square", way.Place.Location)
+	}
+
+	if got[2].Err == nil || got[2].Raw != "way/11" {
+		t.Errorf("Import() way with a missing node = %+v, want an error", got[2])
+	}
+}
+
+func Test_centroid(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []entity.Point
+		want   entity.Point
+	}{
+		{
+			name:   "closed L-shape",
+			points: []entity.Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 2}, {Lat: 1, Lon: 2}, {Lat: 1, Lon: 1}, {Lat: 2, Lon: 1}, {Lat: 2, Lon: 0}, {Lat: 0, Lon: 0}},
+			want:   entity.Point{Lat: 5.0 / 6, Lon: 5.0 / 6},
+		},
+		{
+			name:   "open way",
+			points: []entity.Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 2}, {Lat: 3, Lon: 4}},
+			want:   entity.Point{Lat: 1, Lon: 2},
+		},
+		{
+			name:   "single node",
+			points: []entity.Point{{Lat: 55.7, Lon: 37.6}},
+			want:   entity.Point{Lat: 55.7, Lon: 37.6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := centroid(tt.points)
+			if math.Abs(got.Lat-tt.want.Lat) > 1e-9 || math.Abs(got.Lon-tt.want.Lon) > 1e-9 {
+				t.Errorf("centroid() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package rejections
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"nearestPlaces/internal/entity"
+	"os"
+	"path/filepath"
+)
+
+// Report writes rejected rows to a file as newline-delimited JSON, one rejection per line.
+type Report struct {
+	path string
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+// Create creates the report file, along with its directory, replacing an earlier report of the same name.
+func Create(path string) (*Report, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report %s: %w", path, err)
+	}
+	w := bufio.NewWriter(file)
+	return &Report{
+		path: path,
+		file: file,
+		w:    w,
+		enc:  json.NewEncoder(w),
+	}, nil
+}
+
+func (r *Report) Path() string {
+	return r.path
+}
+
+func (r *Report) Add(rejection entity.Rejection) error {
+	if err := r.enc.Encode(rejection); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", r.path, err)
+	}
+	return nil
+}
+
+func (r *Report) Close() error {
+	if err := r.w.Flush(); err != nil {
+		r.file.Close()
+		return fmt.Errorf("failed to write report %s: %w", r.path, err)
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,150 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Inspect describes the indices the name stands for: the index itself, or every index an alias points to.
+func (e *Storage) Inspect(ctx context.Context, name string) ([]*entity.IndexInfo, error) {
+	const op = "infrastructure.repository.elastic.Inspect"
+	log := e.log.With(
+		slog.String("op", op),
+		slog.String("index", name),
+	)
+	resp, err := e.client.Cat.Indices(
+		e.client.Cat.Indices.WithContext(ctx),
+		e.client.Cat.Indices.WithIndex(name),
+		e.client.Cat.Indices.WithH("index", "docs.count", "store.size"),
+		e.client.Cat.Indices.WithBytes("b"),
+		e.client.Cat.Indices.WithFormat("json"),
+	)
+	if err != nil {
+		log.Error("failed to list indices", sl.Err(err))
+		return nil, fmt.Errorf("error while listing indices: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("index %s: %w", name, entity.ErrNotFound)
+	}
+	var indices []struct {
+		Index string `json:"index"`
+		Docs  string `json:"docs.count"`
+		Size  string `json:"store.size"`
+	}
+	if err = decodeResponse(resp, &indices); err != nil {
+		log.Error("failed to list indices", sl.Err(err))
+		return nil, err
+	}
+
+	resp, err = e.client.Indices.GetMapping(
+		e.client.Indices.GetMapping.WithContext(ctx),
+		e.client.Indices.GetMapping.WithIndex(name),
+	)
+	if err != nil {
+		log.Error("failed to get mappings", sl.Err(err))
+		return nil, fmt.Errorf("error while getting mappings: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	var mappings map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err = decodeResponse(resp, &mappings); err != nil {
+		log.Error("failed to get mappings", sl.Err(err))
+		return nil, err
+	}
+
+	infos := make([]*entity.IndexInfo, 0, len(indices))
+	for _, idx := range indices {
+		docs, _ := strconv.Atoi(idx.Docs)
+		size, _ := strconv.ParseInt(idx.Size, 10, 64)
+		infos = append(infos, &entity.IndexInfo{
+			Name:     idx.Index,
+			Docs:     docs,
+			Bytes:    size,
+			Mappings: mappings[idx.Index].Mappings,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// Aliases lists the aliases of the cluster, hidden ones aside, with the indices they point to.
+func (e *Storage) Aliases(ctx context.Context) ([]*entity.Alias, error) {
+	const op = "infrastructure.repository.elastic.Aliases"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	resp, err := e.client.Cat.Aliases(
+		e.client.Cat.Aliases.WithContext(ctx),
+		e.client.Cat.Aliases.WithH("alias", "index"),
+		e.client.Cat.Aliases.WithFormat("json"),
+	)
+	if err != nil {
+		log.Error("failed to list aliases", sl.Err(err))
+		return nil, fmt.Errorf("error while listing aliases: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	var rows []struct {
+		Alias string `json:"alias"`
+		Index string `json:"index"`
+	}
+	if err = decodeResponse(resp, &rows); err != nil {
+		log.Error("failed to list aliases", sl.Err(err))
+		return nil, err
+	}
+
+	byName := make(map[string]*entity.Alias)
+	aliases := make([]*entity.Alias, 0, len(rows))
+	for _, row := range rows {
+		if row.Alias == "" || row.Alias[0] == '.' {
+			continue
+		}
+		a, ok := byName[row.Alias]
+		if !ok {
+			a = &entity.Alias{Name: row.Alias}
+			byName[row.Alias] = a
+			aliases = append(aliases, a)
+		}
+		a.Indices = append(a.Indices, row.Index)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Name < aliases[j].Name
+	})
+	for _, a := range aliases {
+		sort.Strings(a.Indices)
+	}
+	return aliases, nil
+}
+
+// Export visits every place of the index or alias, in no particular order.
+func (e *Storage) Export(ctx context.Context, name string, visit func(place *entity.Restaurant) error) error {
+	const op = "infrastructure.repository.elastic.Export"
+	log := e.log.With(
+		slog.String("op", op),
+		slog.String("index", name),
+	)
+	query := map[string]interface{}{
+		"_source": []string{"id", "name", "address", "phone", "location", "extra"},
+	}
+	err := e.scan(ctx, name, query, func(hit *searchHit) error {
+		if hit.Source == nil {
+			return nil
+		}
+		return visit(hit.Source)
+	})
+	if err != nil {
+		log.Error("failed to export places", sl.Err(err))
+		return err
+	}
+	return nil
+}
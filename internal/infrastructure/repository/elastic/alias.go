@@ -0,0 +1,238 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (e *Storage) generationName(t time.Time) string {
+	return entity.GenerationName(e.index, t)
+}
+
+// parseGeneration returns the creation time encoded in a generation name.
+func (e *Storage) parseGeneration(index string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(index, e.index+"-")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(entity.GenerationLayout, suffix)
+	return t, err == nil
+}
+
+// aliasHolders returns the indices the alias currently points to.
+func (e *Storage) aliasHolders(ctx context.Context) ([]string, error) {
+	resp, err := e.client.Indices.GetAlias(
+		e.client.Indices.GetAlias.WithContext(ctx),
+		e.client.Indices.GetAlias.WithName(e.index),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting alias: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("error while getting alias: %w", responseError(resp))
+	}
+
+	var respBody map[string]json.RawMessage
+	if err = json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, fmt.Errorf("error while decoding alias: %w", err)
+	}
+	holders := make([]string, 0, len(respBody))
+	for index := range respBody {
+		holders = append(holders, index)
+	}
+	return holders, nil
+}
+
+// CountDocuments refreshes the index and returns the number of searchable documents in it.
+func (e *Storage) CountDocuments(ctx context.Context, index string) (int, error) {
+	const op = "infrastructure.repository.elastic.CountDocuments"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	resp, err := e.client.Indices.Refresh(
+		e.client.Indices.Refresh.WithContext(ctx),
+		e.client.Indices.Refresh.WithIndex(index),
+	)
+	if err != nil {
+		log.Error("failed to refresh index", sl.Err(err))
+		return 0, fmt.Errorf("error while refreshing index: %w", transportError(err))
+	}
+	resp.Body.Close()
+	if resp.IsError() {
+		log.Error("failed to refresh index", slog.String("status", resp.Status()))
+		return 0, fmt.Errorf("error while refreshing index: %w", responseError(resp))
+	}
+
+	resp, err = e.client.Count(
+		e.client.Count.WithContext(ctx),
+		e.client.Count.WithIndex(index),
+	)
+	if err != nil {
+		log.Error("failed to count documents", sl.Err(err))
+		return 0, fmt.Errorf("error while counting documents: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		log.Error("failed to count documents", slog.String("status", resp.Status()))
+		return 0, fmt.Errorf("error while counting documents: %w", responseError(resp))
+	}
+
+	var respBody struct {
+		Count int `json:"count"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		log.Error("failed to unmarshal response", sl.Err(err))
+		return 0, err
+	}
+	return respBody.Count, nil
+}
+
+// Generations lists the generations of the index, newest first.
+func (e *Storage) Generations(ctx context.Context) ([]*entity.Generation, error) {
+	const op = "infrastructure.repository.elastic.Generations"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	resp, err := e.client.Cat.Indices(
+		e.client.Cat.Indices.WithContext(ctx),
+		e.client.Cat.Indices.WithIndex(e.index+"-*"),
+		e.client.Cat.Indices.WithH("index", "docs.count"),
+		e.client.Cat.Indices.WithFormat("json"),
+	)
+	if err != nil {
+		log.Error("failed to list indices", sl.Err(err))
+		return nil, fmt.Errorf("error while listing indices: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		log.Error("failed to list indices", slog.String("status", resp.Status()))
+		return nil, fmt.Errorf("error while listing indices: %w", responseError(resp))
+	}
+
+	var indices []struct {
+		Index string `json:"index"`
+		Docs  string `json:"docs.count"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&indices); err != nil {
+		log.Error("failed to unmarshal response", sl.Err(err))
+		return nil, err
+	}
+
+	holders, err := e.aliasHolders(ctx)
+	if err != nil {
+		log.Error("failed to get alias", sl.Err(err))
+		return nil, err
+	}
+	current := make(map[string]bool, len(holders))
+	for _, h := range holders {
+		current[h] = true
+	}
+
+	generations := make([]*entity.Generation, 0, len(indices))
+	for _, idx := range indices {
+		created, ok := e.parseGeneration(idx.Index)
+		if !ok {
+			continue
+		}
+		docs, _ := strconv.Atoi(idx.Docs)
+		generations = append(generations, &entity.Generation{
+			Name:    idx.Index,
+			Created: created,
+			Docs:    docs,
+			Current: current[idx.Index],
+		})
+	}
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].Created.After(generations[j].Created)
+	})
+	return generations, nil
+}
+
+// SwitchAlias atomically points the alias to the given index and detaches it from every other one.
+// A concrete index left with the alias name by older versions is removed in the same request.
+func (e *Storage) SwitchAlias(ctx context.Context, index string) error {
+	const op = "infrastructure.repository.elastic.SwitchAlias"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	holders, err := e.aliasHolders(ctx)
+	if err != nil {
+		log.Error("failed to get alias", sl.Err(err))
+		return err
+	}
+
+	actions := []map[string]interface{}{
+		{"add": map[string]interface{}{"index": index, "alias": e.index}},
+	}
+	for _, h := range holders {
+		if h == index {
+			continue
+		}
+		actions = append(actions, map[string]interface{}{
+			"remove": map[string]interface{}{"index": h, "alias": e.index},
+		})
+	}
+	if len(holders) == 0 {
+		resp, err := e.client.Indices.Exists([]string{e.index}, e.client.Indices.Exists.WithContext(ctx))
+		if err != nil {
+			log.Error("failed to check if index exists", sl.Err(err))
+			return fmt.Errorf("error while checking if index exists: %w", transportError(err))
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			actions = append(actions, map[string]interface{}{
+				"remove_index": map[string]interface{}{"index": e.index},
+			})
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		log.Error("failed to marshal actions", sl.Err(err))
+		return err
+	}
+	resp, err := e.client.Indices.UpdateAliases(bytes.NewReader(body), e.client.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		log.Error("failed to update aliases", sl.Err(err))
+		return fmt.Errorf("error while updating aliases: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		log.Error("failed to update aliases", slog.String("status", resp.Status()))
+		return fmt.Errorf("error while updating aliases: %w", responseError(resp))
+	}
+	return nil
+}
+
+func (e *Storage) DeleteIndex(ctx context.Context, index string) error {
+	const op = "infrastructure.repository.elastic.DeleteIndex"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	resp, err := e.client.Indices.Delete([]string{index}, e.client.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		log.Error("failed to delete index", sl.Err(err))
+		return fmt.Errorf("error while deleting index: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		log.Error("failed to delete index", slog.String("status", resp.Status()))
+		return fmt.Errorf("error while deleting index: %w", responseError(resp))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package elastic
+
+import (
+	"context"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+)
+
+// GetClusters groups the places inside the box by map tiles of the given precision with a geotile_grid
+// aggregation, returning at most size clusters, the most populated first, and the number of places in the box.
+func (e *Storage) GetClusters(ctx context.Context, bbox *entity.BBox, precision, size int) ([]*entity.Cluster, int, error) {
+	const op = "infrastructure.repository.elastic.GetClusters"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	query := map[string]interface{}{
+		"size":             0,
+		"track_total_hits": true,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": areaFilter(&entity.Area{BBox: bbox}),
+			},
+		},
+		"aggs": map[string]interface{}{
+			"clusters": map[string]interface{}{
+				"geotile_grid": map[string]interface{}{
+					"field":     "location",
+					"precision": precision,
+					"size":      size,
+				},
+				"aggs": map[string]interface{}{
+					"centroid": map[string]interface{}{
+						"geo_centroid": map[string]interface{}{
+							"field": "location",
+						},
+					},
+				},
+			},
+		},
+	}
+	resp, err := e.search(ctx, query)
+	if err != nil {
+		log.Error("failed to search in index", sl.Err(err))
+		return nil, 0, err
+	}
+
+	buckets := resp.Aggregations.Clusters.Buckets
+	clusters := make([]*entity.Cluster, 0, len(buckets))
+	for _, b := range buckets {
+		clusters = append(clusters, &entity.Cluster{
+			Tile:   b.Key,
+			Count:  b.DocCount,
+			Center: b.Centroid.Location,
+		})
+	}
+	return clusters, resp.Hits.Total.Value, nil
+}
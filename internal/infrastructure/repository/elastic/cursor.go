@@ -0,0 +1,224 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+	"net/http"
+)
+
+// pitKeepAlive is how long a point in time stays open between two page requests.
+// Every open point in time pins the segments it reads, so it is kept short;
+// listings read to the end release theirs at once.
+const pitKeepAlive = "5m"
+
+// cursor is the decoded form of the opaque page token handed out to clients.
+// It pins the point in time the listing was started at and the sort values of the boundary hit.
+type cursor struct {
+	PIT    string            `json:"pit"`
+	After  []json.RawMessage `json:"after"`
+	Before bool              `json:"before,omitempty"`
+}
+
+func (c *cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, entity.ErrInvalidCursor
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(b, c); err != nil || c.PIT == "" || len(c.After) == 0 {
+		return nil, entity.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (e *Storage) openPointInTime(ctx context.Context, index string) (string, error) {
+	const op = "infrastructure.repository.elastic.openPointInTime"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	resp, err := e.client.OpenPointInTime([]string{index}, pitKeepAlive, e.client.OpenPointInTime.WithContext(ctx))
+	if err != nil {
+		log.Error("failed to open point in time", sl.Err(err))
+		return "", transportError(err)
+	}
+	defer resp.Body.Close()
+
+	var respBody struct {
+		ID string `json:"id"`
+	}
+	if err = decodeResponse(resp, &respBody); err != nil {
+		log.Error("failed to open point in time", sl.Err(err))
+		return "", err
+	}
+	return respBody.ID, nil
+}
+
+// scanPageSize is how many documents a page of a scan reads.
+const scanPageSize = 10000
+
+// scan visits every document of the index within a point in time, a page at a time.
+// The query picks what the hits hold, e.g. "_source" or "docvalue_fields"; paging is added to it.
+func (e *Storage) scan(ctx context.Context, index string, query map[string]interface{}, visit func(hit *searchHit) error) error {
+	pit, err := e.openPointInTime(ctx, index)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e.closePointInTime(pit)
+	}()
+
+	query["size"] = scanPageSize
+	query["sort"] = []interface{}{
+		map[string]interface{}{"_shard_doc": "asc"},
+	}
+	for {
+		query["pit"] = map[string]interface{}{
+			"id":         pit,
+			"keep_alive": pitKeepAlive,
+		}
+		resp, err := e.search(ctx, query)
+		if err != nil {
+			return err
+		}
+		if resp.PIT != "" {
+			pit = resp.PIT
+		}
+		for i := range resp.Hits.Hits {
+			if err := visit(&resp.Hits.Hits[i]); err != nil {
+				return err
+			}
+		}
+		if len(resp.Hits.Hits) < scanPageSize {
+			return nil
+		}
+		query["search_after"] = resp.Hits.Hits[len(resp.Hits.Hits)-1].Sort
+	}
+}
+
+func (e *Storage) closePointInTime(pit string) {
+	body, _ := json.Marshal(map[string]string{"id": pit})
+	resp, err := e.client.ClosePointInTime(e.client.ClosePointInTime.WithBody(bytes.NewReader(body)))
+	if err != nil {
+		e.log.Warn("failed to close point in time", sl.Err(err))
+		return
+	}
+	resp.Body.Close()
+}
+
+// GetPlacesByCursor returns the page of places adjacent to the given cursor.
+// An empty cursor opens a new point in time and returns the first page.
+// Places are ordered by the shard doc tiebreaker, which is stable within a point in time.
+// The point in time is closed once the last page is served, or when the search opening it fails.
+func (e *Storage) GetPlacesByCursor(ctx context.Context, token string, limit int) ([]*entity.Restaurant, int, entity.Cursors, error) {
+	const op = "infrastructure.repository.elastic.GetPlacesByCursor"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	var cur *cursor
+	opened := token == ""
+	if opened {
+		pit, err := e.openPointInTime(ctx, e.index)
+		if err != nil {
+			return nil, 0, entity.Cursors{}, err
+		}
+		cur = &cursor{PIT: pit}
+	} else {
+		var err error
+		cur, err = decodeCursor(token)
+		if err != nil {
+			log.Error("failed to decode cursor", sl.Err(err))
+			return nil, 0, entity.Cursors{}, err
+		}
+	}
+
+	order := "asc"
+	if cur.Before {
+		order = "desc"
+	}
+	query := map[string]interface{}{
+		"size": limit + 1,
+		"pit": map[string]interface{}{
+			"id":         cur.PIT,
+			"keep_alive": pitKeepAlive,
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"_shard_doc": order},
+		},
+		"track_total_hits": true,
+	}
+	if len(cur.After) > 0 {
+		query["search_after"] = cur.After
+	}
+	resp, err := e.search(ctx, query)
+	var respErr *ResponseError
+	if errors.As(err, &respErr) && respErr.Status == http.StatusNotFound {
+		log.Error("point in time is missing or expired", sl.Err(err))
+		return nil, 0, entity.Cursors{}, entity.ErrInvalidCursor
+	} else if err != nil {
+		log.Error("failed to search in index", sl.Err(err))
+		if opened {
+			e.closePointInTime(cur.PIT)
+		}
+		return nil, 0, entity.Cursors{}, err
+	}
+
+	rests, cursors, last := cursorPage(cur, resp, limit)
+	if last {
+		pit := cur.PIT
+		if resp.PIT != "" {
+			pit = resp.PIT
+		}
+		e.closePointInTime(pit)
+	}
+	return rests, resp.Hits.Total.Value, cursors, nil
+}
+
+// cursorPage turns the reply to a page request into the page and the cursors of its neighbours.
+// It tells whether the page is the last one, after which the listing is over: its point in time is
+// released, so the last page has no cursors and paging back means starting over.
+func cursorPage(cur *cursor, resp *searchResponse, limit int) ([]*entity.Restaurant, entity.Cursors, bool) {
+	hits := resp.Hits.Hits
+	hasMore := len(hits) > limit
+	if hasMore {
+		hits = hits[:limit]
+	}
+	if cur.Before {
+		for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+			hits[i], hits[j] = hits[j], hits[i]
+		}
+	}
+
+	rests := make([]*entity.Restaurant, 0, len(hits))
+	for _, hit := range hits {
+		rests = append(rests, hit.Source)
+	}
+
+	// paging backwards always leaves the boundary hit ahead of the page
+	hasNext := len(hits) > 0 && (hasMore || cur.Before)
+	if !hasNext {
+		return rests, entity.Cursors{}, true
+	}
+	pit := cur.PIT
+	if resp.PIT != "" {
+		pit = resp.PIT
+	}
+	first, last := hits[0].Sort, hits[len(hits)-1].Sort
+	cursors := entity.Cursors{
+		Next: (&cursor{PIT: pit, After: last}).encode(),
+	}
+	if len(cur.After) > 0 && !cur.Before || cur.Before && hasMore {
+		cursors.Prev = (&cursor{PIT: pit, After: first, Before: true}).encode()
+	}
+	return rests, cursors, false
+}
@@ -0,0 +1,127 @@
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hitsBody is a search reply holding a hit per ID, each sorted by its ID.
+func hitsBody(ids ...int) string {
+	hits := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hits = append(hits, fmt.Sprintf(`{"_id":"%d","_source":{"id":"%d"},"sort":[%d]}`, id, id, id))
+	}
+	return fmt.Sprintf(`{"pit_id":"pit-2","hits":{"total":{"value":5},"hits":[%s]}}`, strings.Join(hits, ","))
+}
+
+// describe shows a cursor as its point in time, direction and boundary, or an empty string for no cursor.
+func describe(t *testing.T, token string) string {
+	t.Helper()
+	if token == "" {
+		return ""
+	}
+	c, err := decodeCursor(token)
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	dir := "after"
+	if c.Before {
+		dir = "before"
+	}
+	return fmt.Sprintf("%s %s %s", c.PIT, dir, c.After[0])
+}
+
+func TestCursorPage(t *testing.T) {
+	after := func(v int, before bool) *cursor {
+		return &cursor{PIT: "pit-1", After: []json.RawMessage{json.RawMessage(fmt.Sprint(v))}, Before: before}
+	}
+	tests := []struct {
+		name     string
+		cur      *cursor
+		body     string
+		want     []string
+		wantNext string
+		wantPrev string
+		wantLast bool
+	}{
+		{
+			name:     "first page",
+			cur:      &cursor{PIT: "pit-1"},
+			body:     hitsBody(1, 2, 3),
+			want:     []string{"1", "2"},
+			wantNext: "pit-2 after 2",
+		},
+		{
+			name:     "middle forward",
+			cur:      after(2, false),
+			body:     hitsBody(3, 4, 5),
+			want:     []string{"3", "4"},
+			wantNext: "pit-2 after 4",
+			wantPrev: "pit-2 before 3",
+		},
+		{
+			name:     "middle backward",
+			cur:      after(5, true),
+			body:     hitsBody(4, 3, 2),
+			want:     []string{"3", "4"},
+			wantNext: "pit-2 after 4",
+			wantPrev: "pit-2 before 3",
+		},
+		{
+			name:     "backward to the first page",
+			cur:      after(3, true),
+			body:     hitsBody(2, 1),
+			want:     []string{"1", "2"},
+			wantNext: "pit-2 after 2",
+		},
+		{
+			name:     "last page",
+			cur:      after(4, false),
+			body:     hitsBody(5),
+			want:     []string{"5"},
+			wantLast: true,
+		},
+		{
+			name:     "single page",
+			cur:      &cursor{PIT: "pit-1"},
+			body:     hitsBody(1, 2),
+			want:     []string{"1", "2"},
+			wantLast: true,
+		},
+		{
+			name:     "empty",
+			cur:      &cursor{PIT: "pit-1"},
+			body:     hitsBody(),
+			want:     []string{},
+			wantLast: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &searchResponse{}
+			if err := decodeResponse(newResponse(200, tt.body), resp); err != nil {
+				t.Fatalf("decodeResponse() error = %v", err)
+			}
+			rests, cursors, last := cursorPage(tt.cur, resp, 2)
+			got := make([]string, 0, len(rests))
+			for _, r := range rests {
+				got = append(got, r.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cursorPage() places = %v, want %v", got, tt.want)
+			}
+			if next := describe(t, cursors.Next); next != tt.wantNext {
+				t.Errorf("cursorPage() next = %q, want %q", next, tt.wantNext)
+			}
+			if prev := describe(t, cursors.Prev); prev != tt.wantPrev {
+				t.Errorf("cursorPage() prev = %q, want %q", prev, tt.wantPrev)
+			}
+			if last != tt.wantLast {
+				t.Errorf("cursorPage() last = %v, want %v", last, tt.wantLast)
+			}
+		})
+	}
+}
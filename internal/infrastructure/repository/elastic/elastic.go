@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/elastic/go-elasticsearch/v7"
 	"github.com/elastic/go-elasticsearch/v7/esapi"
@@ -13,24 +12,37 @@ import (
 	"nearestPlaces/internal/entity"
 	"nearestPlaces/internal/lib/logger/sl"
 	"net/http"
+	"time"
 )
 
 type Storage struct {
 	log    *slog.Logger
 	client *elasticsearch.Client
 	index  string
+	bulk   BulkOptions
 }
 
-func New(log *slog.Logger, es *elasticsearch.Client, index string) *Storage {
+// BulkOptions tune the bulk indexer that loads data sets. Zero values leave the esutil defaults.
+type BulkOptions struct {
+	// Workers is how many bulk requests may be in flight at once.
+	Workers int
+	// FlushBytes is the body size at which a bulk request is sent.
+	FlushBytes int
+	// FlushInterval is the longest a document waits before its bulk request is sent.
+	FlushInterval time.Duration
+}
+
+func New(log *slog.Logger, es *elasticsearch.Client, index string, bulk BulkOptions) *Storage {
 	result := &Storage{
 		log:    log,
 		client: es,
 		index:  index,
+		bulk:   bulk,
 	}
 	return result
 }
 
-func (e *Storage) expandMaxResultWindow() error {
+func (e *Storage) expandMaxResultWindow(ctx context.Context, index string) error {
 	const op = "infrastructure.repository.elastic.expandMaxResultWindow"
 	log := e.log.With(
 		slog.String("op", op),
@@ -47,122 +59,93 @@ func (e *Storage) expandMaxResultWindow() error {
 	}
 
 	req := esapi.IndicesPutSettingsRequest{
-		Index: []string{e.index},
+		Index: []string{index},
 		Body:  bytes.NewReader(body),
 	}
 
-	res, err := req.Do(context.Background(), e.client)
+	res, err := req.Do(ctx, e.client)
 	if err != nil {
 		log.Error("failed to update settings", sl.Err(err))
-		return err
+		return transportError(err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
 		log.Error("status is not 200")
-		return errors.New("status is not 200")
+		return fmt.Errorf("error while updating settings: %w", responseError(res))
 	}
 	return nil
 }
 
-func (e *Storage) GetPlaces(limit, offset int) ([]*entity.Restaurant, int, error) {
+func (e *Storage) GetPlaces(ctx context.Context, limit, offset int) ([]*entity.Restaurant, int, error) {
 	const op = "infrastructure.repository.elastic.GetPlaces"
 	log := e.log.With(
 		slog.String("op", op),
 	)
 	query := map[string]interface{}{
-		"size": limit,
-		"from": offset,
-	}
-	body, err := json.Marshal(query)
-	if err != nil {
-		log.Error("failed to marshal query", sl.Err(err))
-		return nil, 0, err
+		"size":             limit,
+		"from":             offset,
+		"track_total_hits": true,
 	}
-
-	req := esapi.SearchRequest{
-		Index:          []string{e.index},
-		Body:           bytes.NewReader(body),
-		TrackTotalHits: true,
-	}
-	resp, err := req.Do(context.Background(), e.client)
+	resp, err := e.search(ctx, query)
 	if err != nil {
 		log.Error("failed to search in index", sl.Err(err))
 		return nil, 0, err
 	}
-	defer resp.Body.Close()
-
-	if resp.IsError() {
-		return nil, 0, err
-	}
-
-	var respBody map[string]interface{}
-	if err = json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return nil, 0, err
-	}
 
-	hits := respBody["hits"].(map[string]interface{})["hits"].([]interface{})
-	rests := make([]*entity.Restaurant, 0, len(hits))
-	for _, hit := range hits {
-		source := hit.(map[string]interface{})["_source"]
-		placeBytes, err := json.Marshal(source)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to marshal place: %s", err.Error())
-		}
-		rest := &entity.Restaurant{}
-		if err := json.Unmarshal(placeBytes, rest); err != nil {
-			return nil, 0, fmt.Errorf("failed to unmarshal place: %s", err.Error())
-		}
-		rests = append(rests, rest)
+	rests := make([]*entity.Restaurant, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		rests = append(rests, hit.Source)
 	}
-	totalHits := int(respBody["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(float64))
-
-	return rests, totalHits, nil
+	return rests, resp.Hits.Total.Value, nil
 }
 
-func (e *Storage) CreateIndex(mappings []byte) error {
+// CreateIndex creates a new generation of the index with the given mappings and returns its name.
+// The generation does not serve queries until it is promoted with SwitchAlias.
+func (e *Storage) CreateIndex(ctx context.Context, mappings []byte) (string, error) {
 	const op = "infrastructure.repository.elastic.CreateIndex"
 	log := e.log.With(
 		slog.String("op", op),
 	)
-	resp, err := e.client.Indices.Exists([]string{e.index})
+	index := e.generationName(time.Now())
+	resp, err := e.client.Indices.Create(index,
+		e.client.Indices.Create.WithContext(ctx),
+		e.client.Indices.Create.WithBody(bytes.NewBuffer(mappings)),
+	)
 	if err != nil {
-		log.Error("failed to check if index exists", sl.Err(err))
-		return fmt.Errorf("error while checking if index exists: %v", err)
+		log.Error("failed to create index", sl.Err(err))
+		return "", fmt.Errorf("error while creating index: %w", transportError(err))
 	}
 	resp.Body.Close()
-	if resp.StatusCode == http.StatusOK {
-		resp, err = e.client.Indices.Delete([]string{e.index})
-		if err != nil || resp.IsError() {
-			log.Error("failed to delete index")
-			return fmt.Errorf("error while deleting index: %s", err)
-		}
-		resp.Body.Close()
+	if resp.IsError() {
+		log.Error("failed to create index", slog.String("status", resp.Status()))
+		return "", fmt.Errorf("error while creating index: %w", responseError(resp))
 	}
-	resp, err = e.client.Indices.Create(e.index, e.client.Indices.Create.WithBody(bytes.NewBuffer(mappings)))
-	if err != nil || resp.IsError() {
-		log.Error("failed to create index")
-		return fmt.Errorf("error while creating index: %v", err)
+	if err = e.expandMaxResultWindow(ctx, index); err != nil {
+		return "", err
 	}
-	err = e.expandMaxResultWindow()
-	resp.Body.Close()
-	return err
+	return index, nil
 }
 
-func (e *Storage) SaveData(data []*entity.Restaurant) error {
+// SaveData indexes the places from the channel until it is closed. Documents are sent
+// in bulk requests while the places still arrive.
+func (e *Storage) SaveData(ctx context.Context, index string, places <-chan *entity.Restaurant) error {
 	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-		Index:  e.index,
-		Client: e.client,
+		Index:         index,
+		Client:        e.client,
+		NumWorkers:    e.bulk.Workers,
+		FlushBytes:    e.bulk.FlushBytes,
+		FlushInterval: e.bulk.FlushInterval,
 	})
 	if err != nil {
 		return fmt.Errorf("error creating bulk indexer: %w", err)
 	}
-	for _, d := range data {
-		clause, err := json.Marshal(d)
+	for d := range places {
+		clause, err := json.Marshal(newDocument(d))
 		if err != nil {
 			return fmt.Errorf("error marshalling data: %w", err)
 		}
-		err = bi.Add(context.Background(), esutil.BulkIndexerItem{
+		err = bi.Add(ctx, esutil.BulkIndexerItem{
 			Action:     "index",
 			Body:       bytes.NewReader(clause),
 			DocumentID: d.ID,
@@ -176,19 +159,22 @@ func (e *Storage) SaveData(data []*entity.Restaurant) error {
 			return fmt.Errorf("saveData: error saving data: %w", err)
 		}
 	}
-	if err := bi.Close(context.Background()); err != nil {
+	if err := bi.Close(ctx); err != nil {
 		return fmt.Errorf("saveData: error closing bulk indexer: %w", err)
 	}
 	return nil
 }
 
-func (e *Storage) GetClosest(lat, lon float64) ([]*entity.Restaurant, error) {
+// GetClosest returns up to limit places nearest to the point, closest first.
+// With a positive radius (in metres) places farther than that are left out.
+// The distance of each place is taken from the sort value.
+func (e *Storage) GetClosest(ctx context.Context, lat, lon float64, limit int, radius float64) ([]*entity.NearbyPlace, error) {
 	const op = "infrastructure.repository.elastic.GetClosest"
 	log := e.log.With(
 		slog.String("op", op),
 	)
 	query := map[string]interface{}{
-		"size": 3,
+		"size": limit,
 		"sort": map[string]interface{}{
 			"_geo_distance": map[string]interface{}{
 				"location": map[string]interface{}{
@@ -196,55 +182,43 @@ func (e *Storage) GetClosest(lat, lon float64) ([]*entity.Restaurant, error) {
 					"lon": lon,
 				},
 				"order":           "asc",
-				"unit":            "km",
+				"unit":            "m",
 				"mode":            "min",
 				"distance_type":   "arc",
 				"ignore_unmapped": true,
 			},
 		},
 	}
-	body, err := json.Marshal(query)
-	if err != nil {
-		log.Error("failed to marshal query", sl.Err(err))
-		return nil, err
-	}
-	req := esapi.SearchRequest{
-		Index: []string{e.index},
-		Body:  bytes.NewReader(body),
+	if radius > 0 {
+		query["query"] = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"geo_distance": map[string]interface{}{
+						"distance":      fmt.Sprintf("%fm", radius),
+						"distance_type": "arc",
+						"location": map[string]interface{}{
+							"lat": lat,
+							"lon": lon,
+						},
+					},
+				},
+			},
+		}
 	}
-	resp, err := req.Do(context.Background(), e.client)
+	resp, err := e.search(ctx, query)
 	if err != nil {
 		log.Error("failed to search in index", sl.Err(err))
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.IsError() {
-		log.Error("failed to search in index")
-		return nil, errors.New("error while search in index")
-	}
-
-	var respBody map[string]interface{}
-	if err = json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		log.Error("failed to unmarshal response", sl.Err(err))
-		return nil, err
-	}
 
-	hits := respBody["hits"].(map[string]interface{})["hits"].([]interface{})
-	rests := make([]*entity.Restaurant, 0, len(hits))
-	for _, hit := range hits {
-		source := hit.(map[string]interface{})["_source"]
-		placeBytes, err := json.Marshal(source)
+	places := make([]*entity.NearbyPlace, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		distance, err := hit.sortFloat(0)
 		if err != nil {
-			log.Error("failed to marshal place", sl.Err(err))
-			return nil, err
-		}
-		rest := &entity.Restaurant{}
-		if err := json.Unmarshal(placeBytes, rest); err != nil {
-			log.Error("failed to unmarshal place", sl.Err(err))
+			log.Error("failed to read distance", sl.Err(err))
 			return nil, err
 		}
-		rests = append(rests, rest)
+		places = append(places, &entity.NearbyPlace{Restaurant: hit.Source, Distance: distance})
 	}
-	return rests, nil
+	return places, nil
 }
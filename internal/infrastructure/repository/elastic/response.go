@@ -0,0 +1,179 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"nearestPlaces/internal/entity"
+	"net/http"
+	"strconv"
+)
+
+// ResponseError is an error reply of Elasticsearch.
+type ResponseError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch: %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("elasticsearch: %d %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// Unwrap tells the kind of the failure by the status of the reply.
+func (e *ResponseError) Unwrap() error {
+	switch e.Status {
+	case http.StatusBadRequest:
+		return entity.ErrInvalidArgument
+	case http.StatusNotFound:
+		return entity.ErrNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return entity.ErrTimeout
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return entity.ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+// transportError tells the kind of a failure to get a reply at all: the request either ran out
+// of its deadline or could not reach the cluster. Cancelled requests are left as they are.
+func transportError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", entity.ErrTimeout, err)
+	default:
+		return fmt.Errorf("%w: %w", entity.ErrUnavailable, err)
+	}
+}
+
+// searchHit is a hit of a search reply. Sort values are kept raw so that they can be passed back verbatim in search_after.
+type searchHit struct {
+	ID        string              `json:"_id"`
+	Score     float64             `json:"_score"`
+	Source    *entity.Restaurant  `json:"_source"`
+	Sort      []json.RawMessage   `json:"sort"`
+	Highlight map[string][]string `json:"highlight"`
+	Fields    map[string][]string `json:"fields"`
+}
+
+// sortFloat returns the i-th sort value as a number. Elasticsearch writes infinite
+// distances of documents without a location as the string "Infinity".
+func (h *searchHit) sortFloat(i int) (float64, error) {
+	if i >= len(h.Sort) {
+		return 0, fmt.Errorf("hit %s has no sort value #%d", h.ID, i)
+	}
+	var f float64
+	if err := json.Unmarshal(h.Sort[i], &f); err == nil {
+		return f, nil
+	}
+	var s string
+	if err := json.Unmarshal(h.Sort[i], &s); err != nil {
+		return 0, fmt.Errorf("hit %s has a non-numeric sort value %s", h.ID, h.Sort[i])
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+type tileBucket struct {
+	Key      string `json:"key"`
+	DocCount int    `json:"doc_count"`
+	Centroid struct {
+		Location entity.Point `json:"location"`
+	} `json:"centroid"`
+}
+
+type suggestOption struct {
+	ID     string             `json:"_id"`
+	Source *entity.Restaurant `json:"_source"`
+}
+
+// searchResponse covers the parts of a search reply the queries of this package ask for.
+type searchResponse struct {
+	PIT  string `json:"pit_id"`
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []searchHit `json:"hits"`
+	} `json:"hits"`
+	Aggregations struct {
+		Clusters struct {
+			Buckets []tileBucket `json:"buckets"`
+		} `json:"clusters"`
+	} `json:"aggregations"`
+	Suggest map[string][]struct {
+		Options []suggestOption `json:"options"`
+	} `json:"suggest"`
+}
+
+// responseError reads the error reply of Elasticsearch. The error may be an object or a bare string.
+func responseError(resp *esapi.Response) *ResponseError {
+	respErr := &ResponseError{Status: resp.StatusCode}
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) != nil || len(body.Error) == 0 {
+		return respErr
+	}
+	var cause struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if json.Unmarshal(body.Error, &cause) == nil {
+		respErr.Type, respErr.Reason = cause.Type, cause.Reason
+	} else {
+		_ = json.Unmarshal(body.Error, &respErr.Reason)
+	}
+	return respErr
+}
+
+// decodeResponse decodes a successful reply into dst and an error reply into a *ResponseError.
+// A reply that is not the JSON expected is an internal error, as retrying would get the same;
+// only a failure to read the reply is told apart as a transport error.
+func decodeResponse(resp *esapi.Response, dst interface{}) error {
+	if resp.IsError() {
+		return responseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+			return fmt.Errorf("%w: malformed response: %w", entity.ErrInternal, err)
+		}
+		return fmt.Errorf("error while decoding response: %w", transportError(err))
+	}
+	return nil
+}
+
+// search runs the query against the index, or against the point in time the query names, and decodes the reply.
+func (e *Storage) search(ctx context.Context, query map[string]interface{}) (*searchResponse, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("error while marshalling query: %w", err)
+	}
+	req := esapi.SearchRequest{
+		Body: bytes.NewReader(body),
+	}
+	if _, ok := query["pit"]; !ok {
+		req.Index = []string{e.index}
+	}
+	resp, err := req.Do(ctx, e.client)
+	if err != nil {
+		return nil, fmt.Errorf("error while searching: %w", transportError(err))
+	}
+	defer resp.Body.Close()
+
+	result := &searchResponse{}
+	if err = decodeResponse(resp, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
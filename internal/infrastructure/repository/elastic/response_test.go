@@ -0,0 +1,244 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7/esapi"
+	"io"
+	"math"
+	"nearestPlaces/internal/entity"
+	"strings"
+	"testing"
+)
+
+func newResponse(status int, body string) *esapi.Response {
+	return &esapi.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantTotal int
+		wantIDs   []string
+		wantErr   *ResponseError
+		wantKind  error
+	}{
+		{
+			name:      "hits",
+			status:    200,
+			body:      `{"hits":{"total":{"value":2},"hits":[{"_id":"1","_source":{"id":"1"},"sort":[12.5]},{"_id":"2","_source":{"id":"2"},"sort":[30]}]}}`,
+			wantTotal: 2,
+			wantIDs:   []string{"1", "2"},
+		},
+		{
+			name:    "error object",
+			status:  404,
+			body:    `{"error":{"root_cause":[],"type":"search_context_missing_exception","reason":"No search context found"},"status":404}`,
+			wantErr: &ResponseError{Status: 404, Type: "search_context_missing_exception", Reason: "No search context found"},
+		},
+		{
+			name:    "error string",
+			status:  400,
+			body:    `{"error":"bad request","status":400}`,
+			wantErr: &ResponseError{Status: 400, Reason: "bad request"},
+		},
+		{
+			name:    "error without body",
+			status:  503,
+			body:    ``,
+			wantErr: &ResponseError{Status: 503},
+		},
+		{
+			name:     "unexpected shape",
+			status:   200,
+			body:     `{"hits":{"total":{"value":1},"hits":"none"}}`,
+			wantKind: entity.ErrInternal,
+		},
+		{
+			name:     "malformed",
+			status:   200,
+			body:     `{"hits":{"total":{"value":1}},}`,
+			wantKind: entity.ErrInternal,
+		},
+		{
+			name:     "cut short",
+			status:   200,
+			body:     `{"hits":{"total":`,
+			wantKind: entity.ErrUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &searchResponse{}
+			err := decodeResponse(newResponse(tt.status, tt.body), got)
+			if tt.wantErr != nil {
+				var respErr *ResponseError
+				if !errors.As(err, &respErr) || *respErr != *tt.wantErr {
+					t.Fatalf("decodeResponse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantKind != nil {
+				if !errors.Is(err, tt.wantKind) {
+					t.Fatalf("decodeResponse() error = %v, want %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeResponse() error = %v", err)
+			}
+			if got.Hits.Total.Value != tt.wantTotal {
+				t.Errorf("decodeResponse() total = %v, want %v", got.Hits.Total.Value, tt.wantTotal)
+			}
+			for i, hit := range got.Hits.Hits {
+				if hit.Source.ID != tt.wantIDs[i] {
+					t.Errorf("decodeResponse() hit #%d = %v, want %v", i, hit.Source.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "bad query", err: &ResponseError{Status: 400, Type: "parsing_exception"}, want: entity.ErrInvalidArgument},
+		{name: "missing index", err: &ResponseError{Status: 404, Type: "index_not_found_exception"}, want: entity.ErrNotFound},
+		{name: "circuit breaker", err: &ResponseError{Status: 429}, want: entity.ErrUnavailable},
+		{name: "cluster down", err: &ResponseError{Status: 503}, want: entity.ErrUnavailable},
+		{name: "deadline", err: transportError(context.DeadlineExceeded), want: entity.ErrTimeout},
+		{name: "connection refused", err: transportError(errors.New("dial tcp: connection refused")), want: entity.ErrUnavailable},
+		{name: "cancelled", err: transportError(context.Canceled), want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.want) {
+				t.Errorf("error %v is not %v", tt.err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHit_SortFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "number", sort: `[1520.75]`, want: 1520.75},
+		{name: "infinity", sort: `["Infinity"]`, want: math.Inf(1)},
+		{name: "missing", sort: `[]`, wantErr: true},
+		{name: "not a number", sort: `[{"a":1}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &searchHit{}
+			if err := json.Unmarshal([]byte(tt.sort), &h.Sort); err != nil {
+				t.Fatal(err)
+			}
+			got, err := h.sortFloat(0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortFloat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("sortFloat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// searchReply builds a reply of a nearest-places query with n hits.
+func searchReply(n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"took":3,"timed_out":false,"hits":{"total":{"value":%d,"relation":"eq"},"max_score":null,"hits":[`, n)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"_index":"places-20241018-153000.000","_type":"_doc","_id":"%d","_score":null,`+
+			`"_source":{"id":"%d","name":"Kafe «Akademija»","address":"gorod Moskva, ulitsa Grekova, dom 3, korpus 1",`+
+			`"phone":"(495) 123-45-67","location":{"lon":37.6173,"lat":55.7558},`+
+			`"suggest_name":["Kafe «Akademija»","«Akademija»"],"suggest_street":["ulitsa Grekova","Grekova"]},`+
+			`"sort":[%d.25]}`, i, i, i*10)
+	}
+	b.WriteString(`]}}`)
+	return b.String()
+}
+
+// decodeGeneric is the former way of reading hits: a generic map walked with type assertions,
+// every source marshalled back and unmarshalled into a place.
+func decodeGeneric(body io.Reader) ([]*entity.NearbyPlace, int, error) {
+	var respBody map[string]interface{}
+	if err := json.NewDecoder(body).Decode(&respBody); err != nil {
+		return nil, 0, err
+	}
+	hits := respBody["hits"].(map[string]interface{})["hits"].([]interface{})
+	places := make([]*entity.NearbyPlace, 0, len(hits))
+	for _, hit := range hits {
+		source := hit.(map[string]interface{})["_source"]
+		placeBytes, err := json.Marshal(source)
+		if err != nil {
+			return nil, 0, err
+		}
+		rest := &entity.Restaurant{}
+		if err := json.Unmarshal(placeBytes, rest); err != nil {
+			return nil, 0, err
+		}
+		place := &entity.NearbyPlace{Restaurant: rest}
+		if sortValues, ok := hit.(map[string]interface{})["sort"].([]interface{}); ok && len(sortValues) > 0 {
+			place.Distance, _ = sortValues[0].(float64)
+		}
+		places = append(places, place)
+	}
+	total := int(respBody["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(float64))
+	return places, total, nil
+}
+
+func decodeTyped(body io.Reader) ([]*entity.NearbyPlace, int, error) {
+	resp := &searchResponse{}
+	if err := decodeResponse(&esapi.Response{StatusCode: 200, Body: io.NopCloser(body)}, resp); err != nil {
+		return nil, 0, err
+	}
+	places := make([]*entity.NearbyPlace, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		distance, err := hit.sortFloat(0)
+		if err != nil {
+			return nil, 0, err
+		}
+		places = append(places, &entity.NearbyPlace{Restaurant: hit.Source, Distance: distance})
+	}
+	return places, resp.Hits.Total.Value, nil
+}
+
+// BenchmarkDecode compares the typed single-pass decoding with the generic one on replies of 10 and 100 hits.
+func BenchmarkDecode(b *testing.B) {
+	decoders := []struct {
+		name   string
+		decode func(io.Reader) ([]*entity.NearbyPlace, int, error)
+	}{
+		{name: "generic", decode: decodeGeneric},
+		{name: "typed", decode: decodeTyped},
+	}
+	for _, n := range []int{10, 100} {
+		reply := searchReply(n)
+		for _, d := range decoders {
+			b.Run(fmt.Sprintf("%s/%d", d.name, n), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(reply)))
+				for i := 0; i < b.N; i++ {
+					if _, _, err := d.decode(strings.NewReader(reply)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
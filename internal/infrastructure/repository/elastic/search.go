@@ -0,0 +1,70 @@
+package elastic
+
+import (
+	"context"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+)
+
+// Search runs a fuzzy full-text query over place names and addresses, a name match weighing twice as much.
+// When near is set, equally relevant places are ordered by distance from it.
+func (e *Storage) Search(ctx context.Context, text string, near *entity.Point, limit, offset int) ([]*entity.SearchHit, int, error) {
+	const op = "infrastructure.repository.elastic.Search"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	sort := []interface{}{"_score"}
+	if near != nil {
+		sort = append(sort, map[string]interface{}{
+			"_geo_distance": map[string]interface{}{
+				"location": map[string]interface{}{
+					"lat": near.Lat,
+					"lon": near.Lon,
+				},
+				"order":           "asc",
+				"unit":            "m",
+				"distance_type":   "arc",
+				"ignore_unmapped": true,
+			},
+		})
+	}
+	query := map[string]interface{}{
+		"size": limit,
+		"from": offset,
+		"query": map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":     text,
+				"fields":    []string{"name^2", "address"},
+				"fuzziness": "AUTO",
+			},
+		},
+		"highlight": map[string]interface{}{
+			"encoder":   "html",
+			"pre_tags":  []string{"<em>"},
+			"post_tags": []string{"</em>"},
+			"fields": map[string]interface{}{
+				"name":    map[string]interface{}{"number_of_fragments": 0},
+				"address": map[string]interface{}{"number_of_fragments": 0},
+			},
+		},
+		"sort":             sort,
+		"track_scores":     true,
+		"track_total_hits": true,
+	}
+	resp, err := e.search(ctx, query)
+	if err != nil {
+		log.Error("failed to search in index", sl.Err(err))
+		return nil, 0, err
+	}
+
+	hits := make([]*entity.SearchHit, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		hits = append(hits, &entity.SearchHit{
+			Restaurant: hit.Source,
+			Score:      hit.Score,
+			Highlights: hit.Highlight,
+		})
+	}
+	return hits, resp.Hits.Total.Value, nil
+}
@@ -0,0 +1,80 @@
+package elastic
+
+import (
+	"context"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+)
+
+// document is a place as stored in the index, along with the inputs of the completion suggesters
+// and the hash of its content that syncs compare.
+type document struct {
+	*entity.Restaurant
+	SuggestName   []string `json:"suggest_name,omitempty"`
+	SuggestStreet []string `json:"suggest_street,omitempty"`
+	ContentHash   string   `json:"content_hash"`
+}
+
+func newDocument(r *entity.Restaurant) *document {
+	return &document{
+		Restaurant:    r,
+		SuggestName:   entity.CompletionInputs(r.Name),
+		SuggestStreet: entity.CompletionInputs(r.Street()),
+		ContentHash:   r.ContentHash(),
+	}
+}
+
+// Suggest returns up to size place names and streets starting with the prefix.
+func (e *Storage) Suggest(ctx context.Context, prefix string, size int) ([]*entity.Suggestion, error) {
+	const op = "infrastructure.repository.elastic.Suggest"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	completion := func(field string) map[string]interface{} {
+		return map[string]interface{}{
+			"prefix": prefix,
+			"completion": map[string]interface{}{
+				"field":           field,
+				"size":            size,
+				"skip_duplicates": true,
+			},
+		}
+	}
+	query := map[string]interface{}{
+		"size":    0,
+		"_source": []string{"name", "address"},
+		"suggest": map[string]interface{}{
+			entity.SuggestionName:   completion("suggest_name"),
+			entity.SuggestionStreet: completion("suggest_street"),
+		},
+	}
+	resp, err := e.search(ctx, query)
+	if err != nil {
+		log.Error("failed to search in index", sl.Err(err))
+		return nil, err
+	}
+
+	suggestions := make([]*entity.Suggestion, 0, 2*size)
+	seen := make(map[entity.Suggestion]bool)
+	for _, kind := range []string{entity.SuggestionName, entity.SuggestionStreet} {
+		for _, entry := range resp.Suggest[kind] {
+			for _, opt := range entry.Options {
+				if opt.Source == nil {
+					continue
+				}
+				text := opt.Source.Name
+				if kind == entity.SuggestionStreet {
+					text = opt.Source.Street()
+				}
+				key := entity.Suggestion{Text: text, Kind: kind}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				suggestions = append(suggestions, &entity.Suggestion{Text: text, Kind: kind, ID: opt.ID})
+			}
+		}
+	}
+	return suggestions, nil
+}
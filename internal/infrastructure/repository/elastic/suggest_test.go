@@ -0,0 +1,21 @@
+package elastic
+
+import (
+	"nearestPlaces/internal/entity"
+	"reflect"
+	"testing"
+)
+
+func TestNewDocument(t *testing.T) {
+	r := &entity.Restaurant{ID: "2", Name: "Kafe «Akademija»", Address: "gorod Moskva, Abel'manovskaja ulitsa, dom 6"}
+	doc := newDocument(r)
+	if want := []string{"Kafe «Akademija»", "«Akademija»"}; !reflect.DeepEqual(doc.SuggestName, want) {
+		t.Errorf("newDocument() name inputs = %q, want %q", doc.SuggestName, want)
+	}
+	if want := []string{"Abel'manovskaja ulitsa", "ulitsa"}; !reflect.DeepEqual(doc.SuggestStreet, want) {
+		t.Errorf("newDocument() street inputs = %q, want %q", doc.SuggestStreet, want)
+	}
+	if doc.ContentHash != r.ContentHash() {
+		t.Errorf("newDocument() hash = %s, want %s", doc.ContentHash, r.ContentHash())
+	}
+}
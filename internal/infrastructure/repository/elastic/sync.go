@@ -0,0 +1,111 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v7/esutil"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+	"sync/atomic"
+)
+
+// Hashes returns the content hashes of all documents of the index by ID. Documents indexed
+// before hashes were stored have an empty hash, so that a sync rewrites them.
+func (e *Storage) Hashes(ctx context.Context, index string) (map[string]string, error) {
+	const op = "infrastructure.repository.elastic.Hashes"
+	log := e.log.With(
+		slog.String("op", op),
+		slog.String("index", index),
+	)
+	hashes := make(map[string]string)
+	query := map[string]interface{}{
+		"_source":         false,
+		"docvalue_fields": []string{"content_hash"},
+	}
+	err := e.scan(ctx, index, query, func(hit *searchHit) error {
+		var hash string
+		if values := hit.Fields["content_hash"]; len(values) > 0 {
+			hash = values[0]
+		}
+		hashes[hit.ID] = hash
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to read hashes", sl.Err(err))
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// ApplyChanges sends the changes of a sync to the index in bulk: added places are indexed,
+// modified ones overwritten and removed ones deleted. A modified place is indexed whole rather than
+// sent as a partial update, which would merge its extra attributes with the stale ones.
+// The changes are checked and encoded before any of them is sent.
+func (e *Storage) ApplyChanges(ctx context.Context, index string, changes []entity.Change) error {
+	const op = "infrastructure.repository.elastic.ApplyChanges"
+	log := e.log.With(
+		slog.String("op", op),
+		slog.String("index", index),
+	)
+	var failed atomic.Int64
+	onFailure := func(ctx context.Context, item esutil.BulkIndexerItem, resp esutil.BulkIndexerResponseItem, err error) {
+		failed.Add(1)
+		if err == nil {
+			err = fmt.Errorf("%s: %s", resp.Error.Type, resp.Error.Reason)
+		}
+		log.Error("failed to apply change", sl.Err(err), slog.String("action", item.Action), slog.String("id", item.DocumentID))
+	}
+	items := make([]esutil.BulkIndexerItem, 0, len(changes))
+	for _, c := range changes {
+		item := esutil.BulkIndexerItem{
+			DocumentID: c.ID,
+			OnFailure:  onFailure,
+		}
+		switch c.Op {
+		case entity.ChangeAdd, entity.ChangeModify:
+			item.Action = "index"
+			body, err := json.Marshal(newDocument(c.Place))
+			if err != nil {
+				return fmt.Errorf("error marshalling data: %w", err)
+			}
+			item.Body = bytes.NewReader(body)
+		case entity.ChangeRemove:
+			item.Action = "delete"
+		default:
+			return fmt.Errorf("unknown change %q", c.Op)
+		}
+		items = append(items, item)
+	}
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Index:         index,
+		Client:        e.client,
+		NumWorkers:    e.bulk.Workers,
+		FlushBytes:    e.bulk.FlushBytes,
+		FlushInterval: e.bulk.FlushInterval,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating bulk indexer: %w", err)
+	}
+	var addErr error
+	for _, item := range items {
+		if addErr = bi.Add(ctx, item); addErr != nil {
+			break
+		}
+	}
+	// The indexer is closed whatever happened, so that its workers stop.
+	closeErr := bi.Close(ctx)
+	if addErr != nil {
+		return fmt.Errorf("applyChanges: error adding change: %w", addErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("applyChanges: error closing bulk indexer: %w", closeErr)
+	}
+	if n := failed.Load(); n > 0 {
+		return fmt.Errorf("applyChanges: %d of %d changes failed", n, len(changes))
+	}
+	return nil
+}
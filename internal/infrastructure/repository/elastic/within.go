@@ -0,0 +1,90 @@
+package elastic
+
+import (
+	"context"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+)
+
+// GetWithin returns a page of the places lying inside the area, in index order.
+func (e *Storage) GetWithin(ctx context.Context, area *entity.Area, limit, offset int) ([]*entity.Restaurant, int, error) {
+	const op = "infrastructure.repository.elastic.GetWithin"
+	log := e.log.With(
+		slog.String("op", op),
+	)
+	query := map[string]interface{}{
+		"size": limit,
+		"from": offset,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": areaFilter(area),
+			},
+		},
+		"sort":             []string{"_doc"},
+		"track_total_hits": true,
+	}
+	resp, err := e.search(ctx, query)
+	if err != nil {
+		log.Error("failed to search in index", sl.Err(err))
+		return nil, 0, err
+	}
+
+	places := make([]*entity.Restaurant, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		places = append(places, hit.Source)
+	}
+	return places, resp.Hits.Total.Value, nil
+}
+
+// areaFilter builds a geo_bounding_box query for a box, or a disjunction of geo_polygon
+// queries with their holes excluded for polygons.
+func areaFilter(area *entity.Area) map[string]interface{} {
+	if b := area.BBox; b != nil {
+		return map[string]interface{}{
+			"geo_bounding_box": map[string]interface{}{
+				"location": map[string]interface{}{
+					"top_left":     map[string]interface{}{"lat": b.North, "lon": b.West},
+					"bottom_right": map[string]interface{}{"lat": b.South, "lon": b.East},
+				},
+			},
+		}
+	}
+
+	polygons := make([]interface{}, 0, len(area.Polygons))
+	for _, pg := range area.Polygons {
+		if len(pg) == 0 {
+			continue
+		}
+		holes := make([]interface{}, 0, len(pg)-1)
+		for _, hole := range pg[1:] {
+			holes = append(holes, ringFilter(hole))
+		}
+		polygons = append(polygons, map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter":   ringFilter(pg[0]),
+				"must_not": holes,
+			},
+		})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               polygons,
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+func ringFilter(ring entity.Ring) map[string]interface{} {
+	points := make([]map[string]float64, 0, len(ring))
+	for _, p := range ring {
+		points = append(points, map[string]float64{"lat": p.Lat, "lon": p.Lon})
+	}
+	return map[string]interface{}{
+		"geo_polygon": map[string]interface{}{
+			"location": map[string]interface{}{
+				"points": points,
+			},
+		},
+	}
+}
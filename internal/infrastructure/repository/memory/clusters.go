@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/geo"
+	"sort"
+)
+
+// GetClusters groups the places inside the box by map tiles of the given precision,
+// returning at most size clusters, the most populated first, and the number of places in the box.
+func (s *Storage) GetClusters(ctx context.Context, bbox *entity.BBox, precision, size int) ([]*entity.Cluster, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clusters := make(map[string]*entity.Cluster)
+	total := 0
+	for _, place := range s.active().places {
+		lat, lon := place.Location.Lat, place.Location.Lon
+		if !bbox.Contains(entity.Point{Lat: lat, Lon: lon}) {
+			continue
+		}
+		total++
+		key := geo.TileKey(lat, lon, precision)
+		c, ok := clusters[key]
+		if !ok {
+			c = &entity.Cluster{Tile: key}
+			clusters[key] = c
+		}
+		c.Count++
+		// The centre accumulates sums until all places are counted.
+		c.Center.Lat += lat
+		c.Center.Lon += lon
+	}
+
+	result := make([]*entity.Cluster, 0, len(clusters))
+	for _, c := range clusters {
+		c.Center.Lat /= float64(c.Count)
+		c.Center.Lon /= float64(c.Count)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Tile < result[j].Tile
+	})
+	return result[:min(size, len(result))], total, nil
+}
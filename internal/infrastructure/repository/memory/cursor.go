@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"nearestPlaces/internal/entity"
+)
+
+// cursor is the decoded form of the opaque page token handed out to clients.
+type cursor struct {
+	Offset int `json:"offset"`
+}
+
+func (c *cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	c := &cursor{}
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, entity.ErrInvalidCursor
+	}
+	if err = json.Unmarshal(b, c); err != nil || c.Offset < 0 {
+		return nil, entity.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func (s *Storage) GetPlacesByCursor(ctx context.Context, token string, limit int) ([]*entity.Restaurant, int, entity.Cursors, error) {
+	cur, err := decodeCursor(token)
+	if err != nil {
+		return nil, 0, entity.Cursors{}, err
+	}
+	rests, total, err := s.GetPlaces(ctx, limit, cur.Offset)
+	if err != nil {
+		return nil, 0, entity.Cursors{}, err
+	}
+
+	var cursors entity.Cursors
+	if next := cur.Offset + limit; next < total {
+		cursors.Next = (&cursor{Offset: next}).encode()
+	}
+	if cur.Offset > 0 {
+		cursors.Prev = (&cursor{Offset: max(0, cur.Offset-limit)}).encode()
+	}
+	return rests, total, cursors, nil
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// kdTree indexes points on the unit sphere by their cartesian coordinates.
+// Nearest neighbours by chord length are nearest neighbours by great-circle distance.
+type kdTree struct {
+	root *kdNode
+}
+
+type kdNode struct {
+	point [3]float64
+	idx   int
+	axis  int
+	left  *kdNode
+	right *kdNode
+}
+
+type kdItem struct {
+	point [3]float64
+	idx   int
+}
+
+func newKDTree(items []kdItem) *kdTree {
+	return &kdTree{root: build(items, 0)}
+}
+
+func build(items []kdItem, depth int) *kdNode {
+	if len(items) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].point[axis] < items[j].point[axis]
+	})
+	mid := len(items) / 2
+	return &kdNode{
+		point: items[mid].point,
+		idx:   items[mid].idx,
+		axis:  axis,
+		left:  build(items[:mid], depth+1),
+		right: build(items[mid+1:], depth+1),
+	}
+}
+
+type neighbour struct {
+	idx   int
+	dist2 float64
+}
+
+// less orders neighbours by distance and breaks ties by insertion order, so results are deterministic.
+func (n neighbour) less(o neighbour) bool {
+	if n.dist2 != o.dist2 {
+		return n.dist2 < o.dist2
+	}
+	return n.idx < o.idx
+}
+
+// neighbourHeap is a max-heap keeping the worst of the current k best candidates on top.
+type neighbourHeap []neighbour
+
+func (h neighbourHeap) Len() int           { return len(h) }
+func (h neighbourHeap) Less(i, j int) bool { return h[j].less(h[i]) }
+func (h neighbourHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *neighbourHeap) Push(x any)        { *h = append(*h, x.(neighbour)) }
+func (h *neighbourHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// nearest returns up to k points closest to q whose squared chord distance does not exceed maxDist2,
+// ordered from the closest one.
+func (t *kdTree) nearest(q [3]float64, k int, maxDist2 float64) []neighbour {
+	if t == nil || t.root == nil || k <= 0 {
+		return nil
+	}
+	h := make(neighbourHeap, 0, k)
+	t.root.search(q, k, maxDist2, &h)
+
+	result := make([]neighbour, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(&h).(neighbour)
+	}
+	return result
+}
+
+func (n *kdNode) search(q [3]float64, k int, maxDist2 float64, h *neighbourHeap) {
+	if n == nil {
+		return
+	}
+	candidate := neighbour{idx: n.idx, dist2: dist2(q, n.point)}
+	if candidate.dist2 <= maxDist2 {
+		if h.Len() < k {
+			heap.Push(h, candidate)
+		} else if candidate.less((*h)[0]) {
+			(*h)[0] = candidate
+			heap.Fix(h, 0)
+		}
+	}
+
+	diff := q[n.axis] - n.point[n.axis]
+	near, far := n.left, n.right
+	if diff > 0 {
+		near, far = n.right, n.left
+	}
+	near.search(q, k, maxDist2, h)
+
+	bound := maxDist2
+	if h.Len() == k && (*h)[0].dist2 < bound {
+		bound = (*h)[0].dist2
+	}
+	if diff*diff <= bound {
+		far.search(q, k, maxDist2, h)
+	}
+}
+
+func dist2(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
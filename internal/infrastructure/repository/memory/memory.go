@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/geo"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Storage keeps places in process memory and answers nearest queries from a k-d tree.
+// Like the Elasticsearch storage it holds several generations of the data set
+// of which only the current one serves queries.
+type Storage struct {
+	log     *slog.Logger
+	name    string
+	mu      sync.RWMutex
+	indices map[string]*dataset
+	current string
+}
+
+type dataset struct {
+	created     time.Time
+	places      []*entity.Restaurant
+	ids         map[string]int
+	tree        *kdTree
+	suggestions []suggestEntry
+}
+
+func New(log *slog.Logger, name string) *Storage {
+	return &Storage{
+		log:     log,
+		name:    name,
+		indices: make(map[string]*dataset),
+	}
+}
+
+// active returns the current generation. Callers must hold the read lock.
+func (s *Storage) active() *dataset {
+	if d, ok := s.indices[s.current]; ok {
+		return d
+	}
+	return &dataset{}
+}
+
+func (s *Storage) lookup(index string) (*dataset, error) {
+	d, ok := s.indices[index]
+	if !ok {
+		return nil, fmt.Errorf("index %s: %w", index, entity.ErrNotFound)
+	}
+	return d, nil
+}
+
+func (s *Storage) CreateIndex(ctx context.Context, mappings []byte) (string, error) {
+	const op = "infrastructure.repository.memory.CreateIndex"
+	log := s.log.With(
+		slog.String("op", op),
+	)
+	if !json.Valid(mappings) {
+		log.Error("mappings are not a valid json")
+		return "", fmt.Errorf("%w: mappings are not a valid json", entity.ErrInvalidArgument)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created := time.Now().UTC()
+	index := entity.GenerationName(s.name, created)
+	for _, ok := s.indices[index]; ok; _, ok = s.indices[index] {
+		created = created.Add(time.Millisecond)
+		index = entity.GenerationName(s.name, created)
+	}
+	s.indices[index] = &dataset{
+		created: created,
+		ids:     make(map[string]int),
+	}
+	return index, nil
+}
+
+// SaveData adds the places from the channel once it is closed. A place with the ID of
+// an earlier one replaces it.
+func (s *Storage) SaveData(ctx context.Context, index string, places <-chan *entity.Restaurant) error {
+	var data []*entity.Restaurant
+	for d := range places {
+		data = append(data, d)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ds, err := s.lookup(index)
+	if err != nil {
+		return err
+	}
+	for _, d := range data {
+		if i, ok := ds.ids[d.ID]; ok {
+			ds.places[i] = d
+			continue
+		}
+		ds.ids[d.ID] = len(ds.places)
+		ds.places = append(ds.places, d)
+	}
+	ds.rebuild()
+	return nil
+}
+
+// Hashes returns the content hashes of all places of the index by ID.
+func (s *Storage) Hashes(ctx context.Context, index string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds, err := s.lookup(index)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(ds.places))
+	for _, p := range ds.places {
+		hashes[p.ID] = p.ContentHash()
+	}
+	return hashes, nil
+}
+
+// ApplyChanges adds, replaces and removes the places of the index as the changes of a sync tell.
+func (s *Storage) ApplyChanges(ctx context.Context, index string, changes []entity.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ds, err := s.lookup(index)
+	if err != nil {
+		return err
+	}
+	updated := make(map[string]*entity.Restaurant)
+	removed := make(map[string]bool)
+	for _, c := range changes {
+		switch c.Op {
+		case entity.ChangeAdd, entity.ChangeModify:
+			updated[c.ID] = c.Place
+		case entity.ChangeRemove:
+			removed[c.ID] = true
+		default:
+			return fmt.Errorf("unknown change %q", c.Op)
+		}
+	}
+	// Queries may still hold the old slice, so the places are gathered into a new one.
+	places := make([]*entity.Restaurant, 0, len(ds.places)+len(updated))
+	for _, p := range ds.places {
+		if removed[p.ID] {
+			continue
+		}
+		if u, ok := updated[p.ID]; ok {
+			p = u
+			delete(updated, p.ID)
+		}
+		places = append(places, p)
+	}
+	for _, c := range changes {
+		if u, ok := updated[c.ID]; ok {
+			places = append(places, u)
+			delete(updated, c.ID)
+		}
+	}
+	ds.places = places
+	ds.ids = make(map[string]int, len(places))
+	for i, p := range places {
+		ds.ids[p.ID] = i
+	}
+	ds.rebuild()
+	return nil
+}
+
+// rebuild indexes the places of the dataset for nearest and suggest queries. Callers must hold the lock.
+func (ds *dataset) rebuild() {
+	items := make([]kdItem, len(ds.places))
+	for i, p := range ds.places {
+		items[i] = kdItem{
+			point: geo.UnitVector(p.Location.Lat, p.Location.Lon),
+			idx:   i,
+		}
+	}
+	ds.tree = newKDTree(items)
+	ds.suggestions = buildSuggestIndex(ds.places)
+}
+
+func (s *Storage) CountDocuments(ctx context.Context, index string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds, err := s.lookup(index)
+	if err != nil {
+		return 0, err
+	}
+	return len(ds.places), nil
+}
+
+func (s *Storage) Generations(ctx context.Context) ([]*entity.Generation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	generations := make([]*entity.Generation, 0, len(s.indices))
+	for name, ds := range s.indices {
+		generations = append(generations, &entity.Generation{
+			Name:    name,
+			Created: ds.created,
+			Docs:    len(ds.places),
+			Current: name == s.current,
+		})
+	}
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].Created.After(generations[j].Created)
+	})
+	return generations, nil
+}
+
+func (s *Storage) SwitchAlias(ctx context.Context, index string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookup(index); err != nil {
+		return err
+	}
+	s.current = index
+	return nil
+}
+
+func (s *Storage) DeleteIndex(ctx context.Context, index string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookup(index); err != nil {
+		return err
+	}
+	delete(s.indices, index)
+	if s.current == index {
+		s.current = ""
+	}
+	return nil
+}
+
+func (s *Storage) GetPlaces(ctx context.Context, limit, offset int) ([]*entity.Restaurant, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds := s.active()
+	total := len(ds.places)
+	if offset >= total {
+		return []*entity.Restaurant{}, total, nil
+	}
+	end := min(offset+limit, total)
+	rests := make([]*entity.Restaurant, end-offset)
+	copy(rests, ds.places[offset:end])
+	return rests, total, nil
+}
+
+// GetClosest returns up to limit places nearest to the point, closest first.
+// With a positive radius (in metres) places farther than that are left out.
+func (s *Storage) GetClosest(ctx context.Context, lat, lon float64, limit int, radius float64) ([]*entity.NearbyPlace, error) {
+	maxDist2 := math.Inf(1)
+	if radius > 0 {
+		chord := geo.DistanceToChord(radius)
+		maxDist2 = chord * chord
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds := s.active()
+	found := ds.tree.nearest(geo.UnitVector(lat, lon), limit, maxDist2)
+	places := make([]*entity.NearbyPlace, 0, len(found))
+	for _, n := range found {
+		rest := ds.places[n.idx]
+		places = append(places, &entity.NearbyPlace{
+			Restaurant: rest,
+			Distance:   geo.Distance(lat, lon, rest.Location.Lat, rest.Location.Lon),
+		})
+	}
+	return places, nil
+}
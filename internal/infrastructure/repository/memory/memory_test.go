@@ -0,0 +1,689 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/geo"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func newPlace(id string, lat, lon float64) *entity.Restaurant {
+	p := &entity.Restaurant{ID: id, Name: "place " + id}
+	p.Location.Lat = lat
+	p.Location.Lon = lon
+	return p
+}
+
+func newTestStorage(t *testing.T, data []*entity.Restaurant) *Storage {
+	t.Helper()
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), "places")
+	fill(t, s, data)
+	return s
+}
+
+func fill(t *testing.T, s *Storage, data []*entity.Restaurant) string {
+	t.Helper()
+	index, err := s.CreateIndex(context.Background(), []byte(`{}`))
+	if err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	places := make(chan *entity.Restaurant, len(data))
+	for _, d := range data {
+		places <- d
+	}
+	close(places)
+	if err := s.SaveData(context.Background(), index, places); err != nil {
+		t.Fatalf("SaveData() error = %v", err)
+	}
+	if err := s.SwitchAlias(context.Background(), index); err != nil {
+		t.Fatalf("SwitchAlias() error = %v", err)
+	}
+	return index
+}
+
+func ids(places []*entity.Restaurant) []string {
+	res := make([]string, 0, len(places))
+	for _, p := range places {
+		res = append(res, p.ID)
+	}
+	return res
+}
+
+func nearbyIDs(places []*entity.NearbyPlace) []string {
+	res := make([]string, 0, len(places))
+	for _, p := range places {
+		res = append(res, p.ID)
+	}
+	return res
+}
+
+func TestStorage_GetClosest(t *testing.T) {
+	data := []*entity.Restaurant{
+		newPlace("0", 55.879001531303366, 37.71456500043604),
+		newPlace("1", 55.7382386551547, 37.6733061300344),
+		newPlace("2", 55.7355114718314, 37.6696475969381),
+		newPlace("3", -33.8688, 151.2093),
+		newPlace("4", 55.67396575768212, 37.66626689310591),
+		newPlace("5", 40.7128, -74.0060),
+	}
+	s := newTestStorage(t, data)
+
+	tests := []struct {
+		name   string
+		lat    float64
+		lon    float64
+		limit  int
+		radius float64
+		want   []string
+	}{
+		{
+			name:  "moscow",
+			lat:   55.674,
+			lon:   37.666,
+			limit: 3,
+			want:  []string{"4", "2", "1"},
+		},
+		{
+			name:   "moscow within radius",
+			lat:    55.674,
+			lon:    37.666,
+			limit:  3,
+			radius: 7000,
+			want:   []string{"4", "2"},
+		},
+		{
+			name:  "moscow single",
+			lat:   55.674,
+			lon:   37.666,
+			limit: 1,
+			want:  []string{"4"},
+		},
+		{
+			name:  "sydney",
+			lat:   -33.86,
+			lon:   151.2,
+			limit: 3,
+			want:  []string{"3", "0", "1"},
+		},
+		{
+			name:  "across antimeridian",
+			lat:   40.7,
+			lon:   -179.9,
+			limit: 3,
+			want:  []string{"5", "0", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetClosest(context.Background(), tt.lat, tt.lon, tt.limit, tt.radius)
+			if err != nil {
+				t.Fatalf("GetClosest() error = %v", err)
+			}
+			if !reflect.DeepEqual(nearbyIDs(got), tt.want) {
+				t.Errorf("GetClosest() got = %v, want %v", nearbyIDs(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_GetClosestMatchesBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	data := make([]*entity.Restaurant, 500)
+	for i := range data {
+		data[i] = newPlace(strconv.Itoa(i), rnd.Float64()*180-90, rnd.Float64()*360-180)
+	}
+	s := newTestStorage(t, data)
+
+	const k = 3
+	for i := 0; i < 100; i++ {
+		lat, lon := rnd.Float64()*180-90, rnd.Float64()*360-180
+		want := make([]*entity.Restaurant, len(data))
+		copy(want, data)
+		sort.SliceStable(want, func(i, j int) bool {
+			return geo.Distance(lat, lon, want[i].Location.Lat, want[i].Location.Lon) <
+				geo.Distance(lat, lon, want[j].Location.Lat, want[j].Location.Lon)
+		})
+
+		got, err := s.GetClosest(context.Background(), lat, lon, k, 0)
+		if err != nil {
+			t.Fatalf("GetClosest() error = %v", err)
+		}
+		if !reflect.DeepEqual(nearbyIDs(got), ids(want[:k])) {
+			t.Fatalf("GetClosest(%v, %v) got = %v, want %v", lat, lon, nearbyIDs(got), ids(want[:k]))
+		}
+		for _, p := range got {
+			if d := geo.Distance(lat, lon, p.Location.Lat, p.Location.Lon); p.Distance != d {
+				t.Fatalf("GetClosest() distance of %s = %v, want %v", p.ID, p.Distance, d)
+			}
+		}
+	}
+}
+
+func TestStorage_GetPlaces(t *testing.T) {
+	data := []*entity.Restaurant{
+		newPlace("0", 1, 1),
+		newPlace("1", 2, 2),
+		newPlace("2", 3, 3),
+		newPlace("1", 4, 4),
+	}
+	s := newTestStorage(t, data)
+
+	tests := []struct {
+		name      string
+		limit     int
+		offset    int
+		want      []string
+		wantTotal int
+	}{
+		{name: "first page", limit: 2, offset: 0, want: []string{"0", "1"}, wantTotal: 3},
+		{name: "last page", limit: 2, offset: 2, want: []string{"2"}, wantTotal: 3},
+		{name: "out of range", limit: 2, offset: 4, want: []string{}, wantTotal: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := s.GetPlaces(context.Background(), tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("GetPlaces() error = %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("GetPlaces() total = %v, want %v", total, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("GetPlaces() got = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func square(west, south, east, north float64) entity.Ring {
+	return entity.Ring{{Lat: south, Lon: west}, {Lat: south, Lon: east}, {Lat: north, Lon: east}, {Lat: north, Lon: west}, {Lat: south, Lon: west}}
+}
+
+func TestStorage_GetWithin(t *testing.T) {
+	data := []*entity.Restaurant{
+		newPlace("0", 1, 1),
+		newPlace("1", 5, 5),
+		newPlace("2", 9, 9),
+		newPlace("3", 0, 179.5),
+		newPlace("4", 0, -179.5),
+		newPlace("5", 5, 8),
+	}
+	s := newTestStorage(t, data)
+
+	tests := []struct {
+		name      string
+		area      *entity.Area
+		limit     int
+		offset    int
+		want      []string
+		wantTotal int
+	}{
+		{
+			name:      "bbox",
+			area:      &entity.Area{BBox: &entity.BBox{West: 0, South: 0, East: 6, North: 6}},
+			limit:     10,
+			want:      []string{"0", "1"},
+			wantTotal: 2,
+		},
+		{
+			name:      "bbox across antimeridian",
+			area:      &entity.Area{BBox: &entity.BBox{West: 179, South: -1, East: -179, North: 1}},
+			limit:     10,
+			want:      []string{"3", "4"},
+			wantTotal: 2,
+		},
+		{
+			name:      "polygon with hole",
+			area:      &entity.Area{Polygons: []entity.Polygon{{square(0, 0, 10, 10), square(4, 4, 6, 6)}}},
+			limit:     10,
+			want:      []string{"0", "2", "5"},
+			wantTotal: 3,
+		},
+		{
+			name: "multipolygon",
+			area: &entity.Area{Polygons: []entity.Polygon{
+				{square(0, 0, 2, 2)},
+				{square(179, -1, 180, 1)},
+			}},
+			limit:     10,
+			want:      []string{"0", "3"},
+			wantTotal: 2,
+		},
+		{
+			name:      "second page",
+			area:      &entity.Area{Polygons: []entity.Polygon{{square(0, 0, 10, 10)}}},
+			limit:     2,
+			offset:    2,
+			want:      []string{"2", "5"},
+			wantTotal: 4,
+		},
+		{
+			name:      "nothing inside",
+			area:      &entity.Area{BBox: &entity.BBox{West: 20, South: 20, East: 30, North: 30}},
+			limit:     10,
+			want:      []string{},
+			wantTotal: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := s.GetWithin(context.Background(), tt.area, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("GetWithin() error = %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("GetWithin() total = %v, want %v", total, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("GetWithin() got = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_GetClusters(t *testing.T) {
+	data := []*entity.Restaurant{
+		newPlace("0", 10, 10),
+		newPlace("1", 20, 20),
+		newPlace("2", 30, 30),
+		newPlace("3", -10, -10),
+		newPlace("4", -20, 150),
+	}
+	s := newTestStorage(t, data)
+	world := &entity.BBox{West: -180, South: -90, East: 180, North: 90}
+
+	tests := []struct {
+		name      string
+		bbox      *entity.BBox
+		precision int
+		size      int
+		want      []entity.Cluster
+		wantTotal int
+	}{
+		{
+			name:      "quadrants",
+			bbox:      world,
+			precision: 1,
+			size:      10,
+			want: []entity.Cluster{
+				{Tile: "1/1/0", Count: 3, Center: entity.Point{Lat: 20, Lon: 20}},
+				{Tile: "1/0/1", Count: 1, Center: entity.Point{Lat: -10, Lon: -10}},
+				{Tile: "1/1/1", Count: 1, Center: entity.Point{Lat: -20, Lon: 150}},
+			},
+			wantTotal: 5,
+		},
+		{
+			name:      "size",
+			bbox:      world,
+			precision: 1,
+			size:      1,
+			want: []entity.Cluster{
+				{Tile: "1/1/0", Count: 3, Center: entity.Point{Lat: 20, Lon: 20}},
+			},
+			wantTotal: 5,
+		},
+		{
+			name:      "bbox",
+			bbox:      &entity.BBox{West: 0, South: 0, East: 25, North: 25},
+			precision: 0,
+			size:      10,
+			want: []entity.Cluster{
+				{Tile: "0/0/0", Count: 2, Center: entity.Point{Lat: 15, Lon: 15}},
+			},
+			wantTotal: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := s.GetClusters(context.Background(), tt.bbox, tt.precision, tt.size)
+			if err != nil {
+				t.Fatalf("GetClusters() error = %v", err)
+			}
+			clusters := make([]entity.Cluster, 0, len(got))
+			for _, c := range got {
+				clusters = append(clusters, *c)
+			}
+			if !reflect.DeepEqual(clusters, tt.want) {
+				t.Errorf("GetClusters() got = %v, want %v", clusters, tt.want)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("GetClusters() total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestStorage_Generations(t *testing.T) {
+	s := newTestStorage(t, []*entity.Restaurant{newPlace("0", 1, 1)})
+	generations, err := s.Generations(context.Background())
+	if err != nil {
+		t.Fatalf("Generations() error = %v", err)
+	}
+	first := generations[0].Name
+
+	second := fill(t, s, []*entity.Restaurant{newPlace("1", 2, 2), newPlace("2", 3, 3)})
+	generations, err = s.Generations(context.Background())
+	if err != nil {
+		t.Fatalf("Generations() error = %v", err)
+	}
+	if len(generations) != 2 || generations[0].Name != second || !generations[0].Current || generations[0].Docs != 2 {
+		t.Fatalf("Generations() got = %+v, want %s current with 2 docs first", generations, second)
+	}
+	if _, total, _ := s.GetPlaces(context.Background(), 10, 0); total != 2 {
+		t.Errorf("GetPlaces() total = %v, want 2", total)
+	}
+
+	if err := s.SwitchAlias(context.Background(), first); err != nil {
+		t.Fatalf("SwitchAlias() error = %v", err)
+	}
+	if got, _ := s.GetClosest(context.Background(), 2, 2, 3, 0); !reflect.DeepEqual(nearbyIDs(got), []string{"0"}) {
+		t.Errorf("GetClosest() after rollback got = %v, want [0]", nearbyIDs(got))
+	}
+
+	if err := s.DeleteIndex(context.Background(), second); err != nil {
+		t.Fatalf("DeleteIndex() error = %v", err)
+	}
+	if err := s.SwitchAlias(context.Background(), second); err == nil {
+		t.Errorf("SwitchAlias() to a deleted index error = nil, want error")
+	}
+}
+
+func TestStorage_ApplyChanges(t *testing.T) {
+	s := newTestStorage(t, []*entity.Restaurant{newPlace("0", 1, 1), newPlace("1", 2, 2), newPlace("2", 3, 3)})
+	generations, err := s.Generations(context.Background())
+	if err != nil {
+		t.Fatalf("Generations() error = %v", err)
+	}
+	index := generations[0].Name
+	before, err := s.Hashes(context.Background(), index)
+	if err != nil {
+		t.Fatalf("Hashes() error = %v", err)
+	}
+
+	moved := newPlace("1", 10, 10)
+	changes := []entity.Change{
+		{Op: entity.ChangeModify, ID: "1", Place: moved},
+		{Op: entity.ChangeRemove, ID: "2"},
+		{Op: entity.ChangeAdd, ID: "3", Place: newPlace("3", 4, 4)},
+	}
+	if err := s.ApplyChanges(context.Background(), index, changes); err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+
+	after, err := s.Hashes(context.Background(), index)
+	if err != nil {
+		t.Fatalf("Hashes() error = %v", err)
+	}
+	if len(after) != 3 || after["0"] != before["0"] || after["1"] != moved.ContentHash() || after["1"] == before["1"] {
+		t.Errorf("Hashes() after changes = %v, before = %v", after, before)
+	}
+	if _, ok := after["2"]; ok {
+		t.Errorf("Hashes() after changes still holds removed place 2")
+	}
+	if got, _ := s.GetClosest(context.Background(), 10, 10, 1, 0); !reflect.DeepEqual(nearbyIDs(got), []string{"1"}) {
+		t.Errorf("GetClosest() after changes got = %v, want [1]", nearbyIDs(got))
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{a: "pizza", b: "pizza", limit: 2, want: 0},
+		{a: "piza", b: "pizza", limit: 1, want: 1},
+		{a: "kitten", b: "sitting", limit: 3, want: 3},
+		{a: "kitten", b: "sitting", limit: 2, want: 3},
+		{a: "ab", b: "abcdef", limit: 2, want: 3},
+		{a: "кафе", b: "кофе", limit: 1, want: 1},
+		{a: "кафе", b: "кино", limit: 1, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+				t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzziness(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{term: "ab", want: 0},
+		{term: "abc", want: 1},
+		{term: "abcde", want: 1},
+		{term: "abcdef", want: 2},
+		// runes are counted, not bytes
+		{term: "еда", want: 1},
+		{term: "кафе", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := fuzziness(tt.term); got != tt.want {
+				t.Errorf("fuzziness(%q) = %d, want %d", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms []string
+		text  string
+		want  string
+	}{
+		{name: "plain", terms: []string{"pizza"}, text: "Pizza Roma", want: "<em>Pizza</em> Roma"},
+		{name: "fuzzy", terms: []string{"roma", "piza"}, text: "Pizza Roma", want: "<em>Pizza</em> <em>Roma</em>"},
+		{name: "html", terms: []string{"jerry"}, text: `Tom & <Jerry> "bar"`, want: `Tom &amp; &lt;<em>Jerry</em>&gt; &#34;bar&#34;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, matched := matchField(tt.terms, tt.text)
+			if got := highlight(tt.text, matched); got != tt.want {
+				t.Errorf("highlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_Search(t *testing.T) {
+	place := func(id, name, address string, lat, lon float64) *entity.Restaurant {
+		p := newPlace(id, lat, lon)
+		p.Name, p.Address = name, address
+		return p
+	}
+	data := []*entity.Restaurant{
+		place("0", "Sushi", "Pizza street 2", 1, 1),
+		place("1", "Pizza Roma", "Lenina 1", 1, 1),
+		place("2", "Coffee", "Arbat 10", 10, 10),
+		place("3", "Coffee", "Arbat 12", 2, 2),
+		place("4", "Tom & <Jerry>", "", 0, 0),
+	}
+	s := newTestStorage(t, data)
+	origin := &entity.Point{Lat: 0, Lon: 0}
+
+	tests := []struct {
+		name      string
+		text      string
+		near      *entity.Point
+		limit     int
+		offset    int
+		want      []string
+		wantTotal int
+	}{
+		{name: "name weighs more than address", text: "pizza", limit: 10, want: []string{"1", "0"}, wantTotal: 2},
+		{name: "fuzzy", text: "piza", limit: 10, want: []string{"1", "0"}, wantTotal: 2},
+		{name: "no match", text: "burger", limit: 10, want: []string{}, wantTotal: 0},
+		{name: "ties in data order", text: "coffee", limit: 10, want: []string{"2", "3"}, wantTotal: 2},
+		{name: "ties by distance", text: "coffee", near: origin, limit: 10, want: []string{"3", "2"}, wantTotal: 2},
+		{name: "limit", text: "coffee", near: origin, limit: 1, want: []string{"3"}, wantTotal: 2},
+		{name: "offset", text: "coffee", near: origin, limit: 1, offset: 1, want: []string{"2"}, wantTotal: 2},
+		{name: "offset past the end", text: "coffee", limit: 10, offset: 5, want: []string{}, wantTotal: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, total, err := s.Search(context.Background(), tt.text, tt.near, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			got := make([]string, 0, len(hits))
+			for _, h := range hits {
+				got = append(got, h.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) || total != tt.wantTotal {
+				t.Errorf("Search() got = %v, total %d, want %v, total %d", got, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+
+	hits, _, err := s.Search(context.Background(), "pizza", nil, 10, 0)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if hits[0].Score != 2*hits[1].Score {
+		t.Errorf("Search() name score = %v, want twice the address score %v", hits[0].Score, hits[1].Score)
+	}
+	if got := hits[0].Highlights["name"]; !reflect.DeepEqual(got, []string{"<em>Pizza</em> Roma"}) {
+		t.Errorf("Search() name highlight = %v", got)
+	}
+	if got := hits[1].Highlights["address"]; !reflect.DeepEqual(got, []string{"<em>Pizza</em> street 2"}) {
+		t.Errorf("Search() address highlight = %v", got)
+	}
+
+	hits, _, err = s.Search(context.Background(), "jerry", nil, 10, 0)
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("Search() hits = %v, error = %v", hits, err)
+	}
+	if got := hits[0].Highlights["name"]; !reflect.DeepEqual(got, []string{"Tom &amp; &lt;<em>Jerry</em>&gt;"}) {
+		t.Errorf("Search() escaped highlight = %v", got)
+	}
+}
+
+func TestStorage_GetPlacesByCursor(t *testing.T) {
+	data := []*entity.Restaurant{
+		newPlace("0", 1, 1),
+		newPlace("1", 2, 2),
+		newPlace("2", 3, 3),
+		newPlace("3", 4, 4),
+		newPlace("4", 5, 5),
+	}
+	s := newTestStorage(t, data)
+	at := func(offset int) string {
+		return (&cursor{Offset: offset}).encode()
+	}
+
+	tests := []struct {
+		name     string
+		cursor   string
+		want     []string
+		wantNext string
+		wantPrev string
+		wantErr  error
+	}{
+		{name: "first page", cursor: "", want: []string{"0", "1"}, wantNext: at(2)},
+		{name: "middle", cursor: at(2), want: []string{"2", "3"}, wantNext: at(4), wantPrev: at(0)},
+		{name: "last page", cursor: at(4), want: []string{"4"}, wantPrev: at(2)},
+		{name: "unaligned", cursor: at(1), want: []string{"1", "2"}, wantNext: at(3), wantPrev: at(0)},
+		{name: "malformed", cursor: "not a cursor", wantErr: entity.ErrInvalidCursor},
+		{name: "negative", cursor: at(-1), wantErr: entity.ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			places, total, cursors, err := s.GetPlacesByCursor(context.Background(), tt.cursor, 2)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetPlacesByCursor() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(ids(places), tt.want) || total != len(data) {
+				t.Errorf("GetPlacesByCursor() got = %v, total %d, want %v, total %d", ids(places), total, tt.want, len(data))
+			}
+			if cursors.Next != tt.wantNext || cursors.Prev != tt.wantPrev {
+				t.Errorf("GetPlacesByCursor() cursors = %+v, want next %q, prev %q", cursors, tt.wantNext, tt.wantPrev)
+			}
+		})
+	}
+}
+
+func TestStorage_Suggest(t *testing.T) {
+	place := func(id, name, address string) *entity.Restaurant {
+		p := newPlace(id, 1, 1)
+		p.Name, p.Address = name, address
+		return p
+	}
+	data := []*entity.Restaurant{
+		place("0", "Kafe «Akademija»", "gorod Moskva, Abel'manovskaja ulitsa, dom 6"),
+		place("1", "Kafe «Akademija»", "gorod Moskva, ulitsa Talalihina, dom 2/1, korpus 1"),
+		place("2", "Akvarel'", "gorod Moskva, Abel'manovskaja ulitsa, dom 8"),
+		place("3", "Rodnik", "gorod Moskva, gorod Zelenograd, korpus 435"),
+	}
+	s := newTestStorage(t, data)
+
+	tests := []struct {
+		name   string
+		prefix string
+		size   int
+		want   []entity.Suggestion
+	}{
+		{
+			name:   "names and streets",
+			prefix: "ab",
+			size:   5,
+			want: []entity.Suggestion{
+				{Text: "Abel'manovskaja ulitsa", Kind: entity.SuggestionStreet, ID: "0"},
+			},
+		},
+		{
+			name:   "word tails, duplicates once",
+			prefix: "AK",
+			size:   5,
+			want: []entity.Suggestion{
+				{Text: "Kafe «Akademija»", Kind: entity.SuggestionName, ID: "0"},
+				{Text: "Akvarel'", Kind: entity.SuggestionName, ID: "2"},
+			},
+		},
+		{
+			name:   "size",
+			prefix: "a",
+			size:   1,
+			want: []entity.Suggestion{
+				{Text: "Kafe «Akademija»", Kind: entity.SuggestionName, ID: "0"},
+				{Text: "Abel'manovskaja ulitsa", Kind: entity.SuggestionStreet, ID: "0"},
+			},
+		},
+		{
+			name:   "no street without a house",
+			prefix: "zel",
+			size:   5,
+			want:   []entity.Suggestion{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Suggest(context.Background(), tt.prefix, tt.size)
+			if err != nil {
+				t.Fatalf("Suggest() error = %v", err)
+			}
+			suggestions := make([]entity.Suggestion, 0, len(got))
+			for _, sg := range got {
+				suggestions = append(suggestions, *sg)
+			}
+			if !reflect.DeepEqual(suggestions, tt.want) {
+				t.Errorf("Suggest() got = %v, want %v", suggestions, tt.want)
+			}
+		})
+	}
+}
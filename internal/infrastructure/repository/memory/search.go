@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/geo"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchCheckEvery is how many places are scored between two checks of the request context.
+const searchCheckEvery = 1024
+
+// searchField is a text field taking part in full-text search and its weight in the score.
+type searchField struct {
+	name   string
+	weight float64
+	value  func(*entity.Restaurant) string
+}
+
+var searchFields = []searchField{
+	{name: "name", weight: 2, value: func(r *entity.Restaurant) string { return r.Name }},
+	{name: "address", weight: 1, value: func(r *entity.Restaurant) string { return r.Address }},
+}
+
+// token is a word of a text with its byte offsets, so that it can be highlighted in place.
+type token struct {
+	term       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = append(tokens, token{term: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// fuzziness mirrors the AUTO fuzziness of Elasticsearch: the number of edits allowed for a term of that length.
+func fuzziness(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the Levenshtein distance between two strings, giving up with limit+1 once it exceeds limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// matchField scores a field against the query terms and returns the field tokens that matched.
+// An exact match of a term counts fully, a fuzzy one proportionally less the more edits it takes.
+func matchField(terms []string, field string) (float64, []token) {
+	tokens := tokenize(field)
+	var score float64
+	var matched []token
+	for _, term := range terms {
+		allowed := fuzziness(term)
+		best := 0.0
+		for _, t := range tokens {
+			d := editDistance(term, t.term, allowed)
+			if d > allowed {
+				continue
+			}
+			matched = append(matched, t)
+			best = max(best, 1-float64(d)/float64(utf8.RuneCountInString(term)+1))
+		}
+		score += best
+	}
+	return score, matched
+}
+
+// highlight wraps the matched tokens of the text in <em> tags and escapes the rest of it.
+func highlight(text string, matched []token) string {
+	sort.Slice(matched, func(i, j int) bool { return matched[i].start < matched[j].start })
+	var b strings.Builder
+	pos := 0
+	for _, t := range matched {
+		if t.start < pos {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</em>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:]))
+	return b.String()
+}
+
+type scoredHit struct {
+	hit      *entity.SearchHit
+	idx      int
+	distance float64
+}
+
+// Search runs a fuzzy full-text query over place names and addresses, a name match weighing twice as much.
+// When near is set, equally relevant places are ordered by distance from it.
+func (s *Storage) Search(ctx context.Context, text string, near *entity.Point, limit, offset int) ([]*entity.SearchHit, int, error) {
+	terms := make([]string, 0)
+	for _, t := range tokenize(text) {
+		terms = append(terms, t.term)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds := s.active()
+	var found []scoredHit
+	for i, place := range ds.places {
+		// scoring every place is the costliest scan of this storage, so it gives up once the request is over
+		if i%searchCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, 0, err
+			}
+		}
+		hit := &entity.SearchHit{Restaurant: place}
+		for _, f := range searchFields {
+			value := f.value(place)
+			score, matched := matchField(terms, value)
+			if len(matched) == 0 {
+				continue
+			}
+			hit.Score += f.weight * score
+			if hit.Highlights == nil {
+				hit.Highlights = make(map[string][]string)
+			}
+			hit.Highlights[f.name] = []string{highlight(value, matched)}
+		}
+		if hit.Score == 0 {
+			continue
+		}
+		h := scoredHit{hit: hit, idx: i}
+		if near != nil {
+			h.distance = geo.Distance(near.Lat, near.Lon, place.Location.Lat, place.Location.Lon)
+		}
+		found = append(found, h)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.hit.Score != b.hit.Score {
+			return a.hit.Score > b.hit.Score
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		return a.idx < b.idx
+	})
+
+	total := len(found)
+	if offset >= total {
+		return []*entity.SearchHit{}, total, nil
+	}
+	found = found[offset:min(offset+limit, total)]
+	hits := make([]*entity.SearchHit, 0, len(found))
+	for _, h := range found {
+		hits = append(hits, h.hit)
+	}
+	return hits, total, nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"nearestPlaces/internal/entity"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// suggestEntry is one completion input: a tail of a name or a street and the suggestion it leads to.
+type suggestEntry struct {
+	key  string
+	text string
+	kind string
+	idx  int
+}
+
+// completionKey normalises a completion input or a typed prefix the way the completion analyzer does:
+// case is folded and leading punctuation dropped.
+func completionKey(s string) string {
+	return strings.ToLower(strings.TrimLeftFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+func buildSuggestIndex(places []*entity.Restaurant) []suggestEntry {
+	var entries []suggestEntry
+	add := func(text, kind string, idx int) {
+		for _, input := range entity.CompletionInputs(text) {
+			entries = append(entries, suggestEntry{
+				key:  completionKey(input),
+				text: text,
+				kind: kind,
+				idx:  idx,
+			})
+		}
+	}
+	for i, p := range places {
+		add(p.Name, entity.SuggestionName, i)
+		add(p.Street(), entity.SuggestionStreet, i)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].key != entries[j].key {
+			return entries[i].key < entries[j].key
+		}
+		return entries[i].idx < entries[j].idx
+	})
+	return entries
+}
+
+// Suggest returns up to size place names and up to size streets starting with the prefix.
+func (s *Storage) Suggest(ctx context.Context, prefix string, size int) ([]*entity.Suggestion, error) {
+	key := completionKey(prefix)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ds := s.active()
+	entries := ds.suggestions
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key >= key })
+
+	counts := make(map[string]int)
+	seen := make(map[entity.Suggestion]bool)
+	var names, streets []*entity.Suggestion
+	for _, e := range entries[start:] {
+		if !strings.HasPrefix(e.key, key) || counts[entity.SuggestionName] >= size && counts[entity.SuggestionStreet] >= size {
+			break
+		}
+		sg := entity.Suggestion{Text: e.text, Kind: e.kind}
+		if seen[sg] || counts[e.kind] >= size {
+			continue
+		}
+		seen[sg] = true
+		counts[e.kind]++
+		sg.ID = ds.places[e.idx].ID
+		if e.kind == entity.SuggestionName {
+			names = append(names, &sg)
+		} else {
+			streets = append(streets, &sg)
+		}
+	}
+	return append(names, streets...), nil
+}
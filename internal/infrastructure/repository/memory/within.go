@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+	"nearestPlaces/internal/entity"
+)
+
+// GetWithin returns a page of the places lying inside the area, in the order they were loaded.
+func (s *Storage) GetWithin(ctx context.Context, area *entity.Area, limit, offset int) ([]*entity.Restaurant, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var found []*entity.Restaurant
+	for _, place := range s.active().places {
+		if area.Contains(entity.Point{Lat: place.Location.Lat, Lon: place.Location.Lon}) {
+			found = append(found, place)
+		}
+	}
+
+	total := len(found)
+	if offset >= total {
+		return []*entity.Restaurant{}, total, nil
+	}
+	return found[offset:min(offset+limit, total)], total, nil
+}
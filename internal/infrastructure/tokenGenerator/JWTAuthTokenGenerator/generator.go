@@ -21,14 +21,24 @@ func New(tokenAuth *jwtauth.JWTAuth, tokenLiveTime time.Duration) *JWTAuth {
 }
 
 func (m *JWTAuth) Generate() (string, error) {
-	_, tokenString, err := m.TokenAuth.Encode(map[string]interface{}{
+	return m.GenerateWithClaims(m.tokenLiveTime, nil)
+}
+
+// GenerateWithClaims generates a token that lives for ttl and carries the given claims,
+// which take precedence over the standard ones.
+func (m *JWTAuth) GenerateWithClaims(ttl time.Duration, extra map[string]interface{}) (string, error) {
+	claims := map[string]interface{}{
 		"iss": "localhost:8888",
 		//"sub": userLogin,
 		"aud": "localhost:8888",
 		"iat": time.Now().UTC().Unix(),
-		"exp": time.Now().UTC().Add(m.tokenLiveTime).Unix(),
+		"exp": time.Now().UTC().Add(ttl).Unix(),
 		"jti": gofakeit.UUID(),
-	})
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	_, tokenString, err := m.TokenAuth.Encode(claims)
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", tokenGenerator.GenerationError, err)
 	}
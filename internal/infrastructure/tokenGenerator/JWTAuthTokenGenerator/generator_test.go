@@ -35,3 +35,25 @@ func TestManager_Generate(t *testing.T) {
 		})
 	}
 }
+
+func TestManager_GenerateWithClaims(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil,
+		jwt.WithAcceptableSkew(time.Second))
+	m := New(tokenAuth, time.Second*30)
+
+	got, err := m.GenerateWithClaims(time.Hour, map[string]interface{}{"admin": true, "sub": "ops"})
+	if err != nil {
+		t.Fatalf("GenerateWithClaims() error = %v", err)
+	}
+	token, err := tokenAuth.Decode(got)
+	if err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	claims := token.PrivateClaims()
+	if claims["admin"] != true || token.Subject() != "ops" {
+		t.Errorf("GenerateWithClaims() claims = %v, subject = %q, want admin and subject ops", claims, token.Subject())
+	}
+	if ttl := time.Until(token.Expiration()); ttl < 59*time.Minute || ttl > time.Hour {
+		t.Errorf("GenerateWithClaims() expires in %v, want an hour", ttl)
+	}
+}
@@ -0,0 +1,64 @@
+package uploads
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"nearestPlaces/internal/infrastructure/csv"
+	"nearestPlaces/internal/usecase"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir keeps uploaded data sets in a directory, each named after the job loading it.
+type Dir struct {
+	dir string
+}
+
+func New(dir string) *Dir {
+	return &Dir{
+		dir: dir,
+	}
+}
+
+// Save writes the data set to <dir>/<id><ext>, keeping the extension of the uploaded file,
+// and its mapping, if any, next to it where the CSV importer looks for it. It returns the path of the data set.
+func (d *Dir) Save(id string, upload *usecase.DatasetUpload) (string, error) {
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	path := filepath.Join(d.dir, id+strings.ToLower(filepath.Ext(upload.Filename)))
+	if err := write(path, upload.Data); err != nil {
+		return "", err
+	}
+	if upload.Mapping != nil {
+		if err := write(csv.MappingPath(path), upload.Mapping); err != nil {
+			os.Remove(path)
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// Remove deletes the data set and its mapping.
+func (d *Dir) Remove(path string) error {
+	err := os.Remove(path)
+	if err := os.Remove(csv.MappingPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return err
+}
+
+func write(path string, r io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,104 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"nearestPlaces/internal/lib/logger/sl"
+	"os"
+	"time"
+)
+
+// Watcher notices changes to files by polling their size and modification time,
+// which works on the bind mounts and network volumes where file events are not delivered.
+type Watcher struct {
+	log      *slog.Logger
+	interval time.Duration
+	quiet    time.Duration
+	paths    []string
+}
+
+// New creates a watcher that checks the files every interval and reports a change
+// once the changed files have stayed the same for the quiet period.
+func New(log *slog.Logger, interval, quiet time.Duration, paths ...string) *Watcher {
+	return &Watcher{
+		log:      log,
+		interval: max(interval, 10*time.Millisecond),
+		quiet:    quiet,
+		paths:    paths,
+	}
+}
+
+// state is what a file looked like when it was last checked. A missing file has the zero state.
+type state struct {
+	size    int64
+	modTime int64
+}
+
+func stat(path string) (state, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state{}, nil
+	}
+	if err != nil {
+		return state{}, err
+	}
+	return state{size: info.Size(), modTime: info.ModTime().UnixNano()}, nil
+}
+
+// Run calls onChange with the paths of the files that changed, until ctx is done. A file being written,
+// or replaced by removing and recreating it, keeps changing, so the change is only reported once the files
+// have stayed the same for the quiet period; onChange is then called with every file changed since the last call.
+// A file that is removed counts as changed, as does one that appears.
+func (w *Watcher) Run(ctx context.Context, onChange func(ctx context.Context, paths []string)) {
+	const op = "infrastructure.watcher.Run"
+	log := w.log.With(
+		slog.String("op", op),
+	)
+	seen := make(map[string]state, len(w.paths))
+	for _, path := range w.paths {
+		s, err := stat(path)
+		if err != nil {
+			log.Error("failed to stat file", sl.Err(err), slog.String("path", path))
+		}
+		seen[path] = s
+	}
+	log.Info("watching files", slog.Any("paths", w.paths), slog.String("interval", w.interval.String()))
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	// changed holds the files changed since the last report; settled is when the last of them changed.
+	changed := make(map[string]bool)
+	var settled time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, path := range w.paths {
+				s, err := stat(path)
+				if err != nil {
+					log.Error("failed to stat file", sl.Err(err), slog.String("path", path))
+					continue
+				}
+				if s != seen[path] {
+					seen[path] = s
+					changed[path] = true
+					settled = now
+					log.Info("file changed", slog.String("path", path), slog.Int64("bytes", s.size))
+				}
+			}
+			if len(changed) == 0 || now.Sub(settled) < w.quiet {
+				continue
+			}
+			paths := make([]string, 0, len(changed))
+			for _, path := range w.paths {
+				if changed[path] {
+					paths = append(paths, path)
+				}
+			}
+			clear(changed)
+			onChange(ctx, paths)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package watcher
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Run(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data.csv")
+	schema := filepath.Join(dir, "schema.json")
+	mapping := filepath.Join(dir, "data.mapping.yaml")
+	for _, path := range []string{data, schema} {
+		if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []string, 4)
+	w := New(slog.New(slog.NewTextHandler(io.Discard, nil)), 10*time.Millisecond, 100*time.Millisecond, data, mapping, schema)
+	go w.Run(ctx, func(ctx context.Context, paths []string) {
+		changes <- paths
+	})
+	time.Sleep(30 * time.Millisecond)
+
+	// A file written in parts is reported once, after it has settled, along with the mapping that appeared.
+	start := time.Now()
+	for _, part := range []string{"v2", "v2, part 2", "v2, part 2, part 3"} {
+		if err := os.WriteFile(data, []byte(part), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+	if err := os.WriteFile(mapping, []byte("delimiter: \";\""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-changes:
+		if want := []string{data, mapping}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Run() changed = %v, want %v", got, want)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("Run() reported the change after %v, before the files settled", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not report the change")
+	}
+	select {
+	case got := <-changes:
+		t.Errorf("Run() reported an extra change %v", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,31 @@
+package response
+
+import (
+	"errors"
+	"nearestPlaces/internal/entity"
+	"time"
+)
+
+// retryAfter is how long clients are asked to wait before retrying a request the storage was unavailable for.
+const retryAfter = 5 * time.Second
+
+// ErrFromDomain answers a failed request according to the kind of the domain error. Invalid parameters
+// are listed one by one, other invalid arguments and missing resources are reported with the error message
+// and remaining failures with the status only.
+func ErrFromDomain(err error) *Problem {
+	var paramErr *entity.ParamError
+	switch {
+	case errors.As(err, &paramErr):
+		return ErrInvalidParam(paramErr.Param, paramErr.Reason)
+	case errors.Is(err, entity.ErrInvalidArgument):
+		return ErrBadRequest(err.Error())
+	case errors.Is(err, entity.ErrNotFound):
+		return ErrNotFoundText(err.Error())
+	case errors.Is(err, entity.ErrUnavailable):
+		return ErrServiceUnavailable(retryAfter)
+	case errors.Is(err, entity.ErrTimeout):
+		return ErrGatewayTimeout()
+	default:
+		return ErrInternal()
+	}
+}
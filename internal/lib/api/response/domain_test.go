@@ -0,0 +1,65 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"nearestPlaces/internal/entity"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrFromDomain(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantType       string
+		wantParams     []InvalidParam
+		wantRetryAfter string
+	}{
+		{name: "invalid argument", err: fmt.Errorf("%w: either 'bbox' or a polygon is required", entity.ErrInvalidArgument), wantStatus: http.StatusBadRequest, wantType: "about:blank"},
+		{name: "invalid param", err: fmt.Errorf("search: %w", &entity.ParamError{Param: "q", Reason: "must not be empty"}), wantStatus: http.StatusBadRequest, wantType: TypeInvalidParams, wantParams: []InvalidParam{{Name: "q", Reason: "must not be empty"}}},
+		{name: "invalid cursor", err: entity.ErrInvalidCursor, wantStatus: http.StatusBadRequest, wantType: "about:blank"},
+		{name: "not found", err: fmt.Errorf("index places: %w", entity.ErrNotFound), wantStatus: http.StatusNotFound, wantType: "about:blank"},
+		{name: "unavailable", err: entity.ErrUnavailable, wantStatus: http.StatusServiceUnavailable, wantType: "about:blank", wantRetryAfter: "5"},
+		{name: "timeout", err: entity.ErrTimeout, wantStatus: http.StatusGatewayTimeout, wantType: "about:blank"},
+		{name: "unknown", err: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantType: "about:blank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/places", nil)
+			r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "req-1"))
+			Render(w, r, ErrFromDomain(tt.err))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ErrFromDomain() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != ContentTypeProblem {
+				t.Errorf("ErrFromDomain() Content-Type = %q, want %q", got, ContentTypeProblem)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("ErrFromDomain() Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			var p Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			if p.Status != tt.wantStatus || p.Type != tt.wantType || p.Instance != "req-1" {
+				t.Errorf("ErrFromDomain() problem = %+v, want status %v, type %q, instance %q", p, tt.wantStatus, tt.wantType, "req-1")
+			}
+			if len(p.InvalidParams) != len(tt.wantParams) {
+				t.Fatalf("ErrFromDomain() invalid params = %v, want %v", p.InvalidParams, tt.wantParams)
+			}
+			for i := range tt.wantParams {
+				if p.InvalidParams[i] != tt.wantParams[i] {
+					t.Errorf("ErrFromDomain() invalid params = %v, want %v", p.InvalidParams, tt.wantParams)
+				}
+			}
+		})
+	}
+}
@@ -1,38 +1,135 @@
 package response
 
 import (
-	"github.com/go-chi/render"
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"html/template"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-type ErrResponse struct {
-	HTTPStatusCode int    `json:"-"`
-	Error          string `json:"error"`
+// ContentTypeProblem is the media type of RFC 7807 problem details.
+const ContentTypeProblem = "application/problem+json"
+
+// TypeInvalidParams is the problem type of requests with invalid parameters, listed in InvalidParams.
+// Other problems are told apart by their status alone and have the type about:blank.
+const TypeInvalidParams = "/problems/invalid-params"
+
+// errorTemplate is the page browser routes render problems with.
+const errorTemplate = "templates/error.html"
+
+// Problem is an RFC 7807 problem detail. Instance is the ID of the failed request.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+	// RetryAfter is sent in the Retry-After header when set.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// InvalidParam tells which request parameter is invalid and why.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
 }
 
-func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
-	render.Status(r, e.HTTPStatusCode)
-	render.SetContentType(render.ContentTypeJSON)
-	return nil
+func newProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
 }
 
-func ErrInternal() render.Renderer {
-	return &ErrResponse{
-		HTTPStatusCode: http.StatusInternalServerError,
-		Error:          http.StatusText(http.StatusInternalServerError),
+func (p *Problem) writeHeader(w http.ResponseWriter, contentType string) {
+	if p.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(p.RetryAfter.Seconds()))))
 	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(p.Status)
 }
 
-func ErrBadRequest(errorText string) render.Renderer {
-	return &ErrResponse{
-		HTTPStatusCode: http.StatusBadRequest,
-		Error:          errorText,
+// Render answers an API request with the problem as application/problem+json.
+func Render(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = middleware.GetReqID(r.Context())
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
+	p.writeHeader(w, ContentTypeProblem)
+	w.Write(body)
 }
 
-func ErrNotFound() render.Renderer {
-	return &ErrResponse{
-		HTTPStatusCode: http.StatusNotFound,
-		Error:          http.StatusText(http.StatusNotFound),
+// RenderHTML answers a browser request with an error page showing the problem.
+func RenderHTML(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = middleware.GetReqID(r.Context())
+	var page bytes.Buffer
+	tmpl, err := template.ParseFiles(errorTemplate)
+	if err == nil {
+		err = tmpl.Execute(&page, p)
 	}
+	if err != nil {
+		http.Error(w, p.Title, p.Status)
+		return
+	}
+	p.writeHeader(w, "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
+
+func ErrInternal() *Problem {
+	return newProblem(http.StatusInternalServerError, "")
+}
+
+func ErrBadRequest(detail string) *Problem {
+	return newProblem(http.StatusBadRequest, detail)
+}
+
+// ErrInvalidParam reports a request parameter that is missing or malformed.
+func ErrInvalidParam(name, reason string) *Problem {
+	return ErrInvalidParams(InvalidParam{Name: name, Reason: reason})
+}
+
+func ErrInvalidParams(params ...InvalidParam) *Problem {
+	p := newProblem(http.StatusBadRequest, "The request parameters are invalid.")
+	p.Type = TypeInvalidParams
+	p.InvalidParams = params
+	return p
+}
+
+func ErrNotFound() *Problem {
+	return newProblem(http.StatusNotFound, "")
+}
+
+func ErrNotFoundText(detail string) *Problem {
+	return newProblem(http.StatusNotFound, detail)
+}
+
+func ErrMethodNotAllowed() *Problem {
+	return newProblem(http.StatusMethodNotAllowed, "")
+}
+
+func ErrUnauthorized(detail string) *Problem {
+	return newProblem(http.StatusUnauthorized, detail)
+}
+
+func ErrForbidden() *Problem {
+	return newProblem(http.StatusForbidden, "")
+}
+
+func ErrGatewayTimeout() *Problem {
+	return newProblem(http.StatusGatewayTimeout, "")
+}
+
+func ErrServiceUnavailable(retryAfter time.Duration) *Problem {
+	p := newProblem(http.StatusServiceUnavailable, "")
+	p.RetryAfter = retryAfter
+	return p
 }
@@ -1,18 +1,47 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
+	"net/netip"
 	"os"
 	"time"
 )
 
+const (
+	StorageElastic = "elastic"
+	StorageMemory  = "memory"
+)
+
+const (
+	IngestReload = "reload"
+	IngestSync   = "sync"
+)
+
 type Config struct {
-	DataPath   string  `yaml:"data_path"`
-	SchemaPath string  `yaml:"schema_path"`
-	Elastic    Elastic `yaml:"elastic"`
-	Server     Server  `yaml:"server"`
-	Token      Token   `yaml:"token"`
+	DataPath   string    `yaml:"data_path"`
+	DataFormat string    `yaml:"data_format"`
+	SchemaPath string    `yaml:"schema_path"`
+	Storage    string    `yaml:"storage" env-default:"elastic"`
+	Index      Index     `yaml:"index"`
+	Elastic    Elastic   `yaml:"elastic"`
+	Server     Server    `yaml:"server"`
+	Budgets    Budgets   `yaml:"budgets"`
+	Token      Token     `yaml:"token"`
+	Recommend  Recommend `yaml:"recommend"`
+	Suggest    Suggest   `yaml:"suggest"`
+	Clusters   Clusters  `yaml:"clusters"`
+	GeoIP      GeoIP     `yaml:"geoip"`
+	Ingest     Ingest    `yaml:"ingest"`
+	Watch      Watch     `yaml:"watch"`
+}
+
+type Index struct {
+	Name string `yaml:"name" env-default:"places"`
+	// Generations is how many timestamped builds of the index are kept for rollback.
+	Generations int `yaml:"generations" env-default:"3"`
 }
 
 type Elastic struct {
@@ -29,10 +58,97 @@ type Server struct {
 	IdleTimeout     time.Duration `yaml:"idle_timeout"`
 }
 
+// Budgets bound the time a request may spend querying storage before it is answered with 504.
+type Budgets struct {
+	// Default applies to routes without a budget of their own. Zero leaves them unbounded.
+	Default time.Duration `yaml:"default" env-default:"3s"`
+	// Routes maps request paths such as /api/search to their budgets.
+	Routes map[string]time.Duration `yaml:"routes"`
+}
+
 type Token struct {
 	Secret string        `yaml:"secret"`
 	TTL    time.Duration `yaml:"ttl"`
 	Skew   time.Duration `yaml:"skew"`
+	// AdminKey is exchanged at /api/get_token for a token carrying the admin claim; empty disables the exchange.
+	AdminKey string `yaml:"admin_key" env:"TOKEN_ADMIN_KEY"`
+}
+
+type Recommend struct {
+	DefaultLimit int `yaml:"default_limit" env-default:"3"`
+	MaxLimit     int `yaml:"max_limit" env-default:"50"`
+	// DefaultRadius is the search radius in metres used when none is requested.
+	// Zero leaves such searches unbounded, which is only allowed when MaxRadius is zero as well.
+	DefaultRadius float64 `yaml:"default_radius" env-default:"1000"`
+	// MaxRadius caps the requested search radius in metres. Zero lifts the cap.
+	MaxRadius float64 `yaml:"max_radius" env-default:"5000"`
+}
+
+type Suggest struct {
+	DefaultSize int `yaml:"default_size" env-default:"5"`
+	MaxSize     int `yaml:"max_size" env-default:"10"`
+}
+
+type Clusters struct {
+	// PlacesZoom is the zoom level from which individual places are returned instead of clusters.
+	PlacesZoom int `yaml:"places_zoom" env-default:"17"`
+	// MaxPlaces caps the number of individual places returned at high zoom levels.
+	MaxPlaces int `yaml:"max_places" env-default:"500"`
+	// MaxClusters caps the number of clusters returned for a viewport.
+	MaxClusters int `yaml:"max_clusters" env-default:"1000"`
+}
+
+// GeoIP approximates the location of clients that send no coordinates to /api/recommend.
+type GeoIP struct {
+	// DBPath is a MaxMind-format City database (.mmdb). Empty turns the fallback off.
+	DBPath string `yaml:"db_path" env:"GEOIP_DB_PATH"`
+	// TrustedProxies are the networks whose X-Forwarded-For header is believed.
+	TrustedProxies []netip.Prefix `yaml:"trusted_proxies"`
+}
+
+// Ingest tunes the pipeline that loads the data set: rows are read, parsed, validated
+// and sent to the storage concurrently, with a bounded number of rows in flight.
+type Ingest struct {
+	// Mode is how the data set is loaded on start: reload builds a new generation,
+	// sync brings the current one in line with the data set in place.
+	Mode string `yaml:"mode" env-default:"reload"`
+	// SkipOnStart leaves the index as it is on start, for servers sharing an index filled by cmd/loader.
+	SkipOnStart bool `yaml:"skip_on_start" env:"INGEST_SKIP_ON_START"`
+	// ParseWorkers parse rows concurrently; the order of the rows is kept.
+	ParseWorkers int `yaml:"parse_workers" env-default:"4"`
+	// Buffer is how many rows may wait between two stages of the pipeline.
+	Buffer int `yaml:"buffer" env-default:"1024"`
+	// IndexWorkers is how many bulk requests may be in flight at once.
+	IndexWorkers int `yaml:"index_workers" env-default:"2"`
+	// FlushBytes is the body size at which a bulk request is sent.
+	FlushBytes int `yaml:"flush_bytes" env-default:"5242880"`
+	// FlushInterval is the longest a place waits before its bulk request is sent.
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+	// ProgressInterval is how often the progress of a load is logged.
+	ProgressInterval time.Duration `yaml:"progress_interval" env-default:"5s"`
+	// Lenient skips rows that cannot be loaded instead of abandoning the whole load.
+	Lenient bool `yaml:"lenient"`
+	// MaxRejectRate fails a lenient load when a larger share of its rows, from 0 to 1, is rejected.
+	MaxRejectRate float64 `yaml:"max_reject_rate" env-default:"0.05"`
+	// ReportDir receives the report of the rows rejected by a load, named after its generation.
+	ReportDir string `yaml:"report_dir" env-default:"reports"`
+	// UploadDir keeps the data sets uploaded through the admin API while they are loaded.
+	UploadDir string `yaml:"upload_dir" env-default:"uploads"`
+	// MaxUploadSize caps the size in bytes of an upload request.
+	MaxUploadSize int64 `yaml:"max_upload_size" env-default:"104857600"`
+	// UploadTimeout is how long an upload request may take to be received and answered,
+	// in place of server.read_timeout, server.write_timeout and the request budget.
+	UploadTimeout time.Duration `yaml:"upload_timeout" env-default:"10m"`
+}
+
+// Watch reloads the data set when it, its mapping or the schema changes on disk.
+type Watch struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often the files are checked.
+	Interval time.Duration `yaml:"interval" env-default:"2s"`
+	// Quiet is how long changed files have to stay the same before they are loaded,
+	// so that a file still being written is not.
+	Quiet time.Duration `yaml:"quiet" env-default:"10s"`
 }
 
 func MustLoad() *Config {
@@ -49,6 +165,21 @@ func MustLoad() *Config {
 	if err := cleanenv.ReadConfig(configPath, &config); err != nil {
 		log.Fatal("cannot read config: ", err)
 	}
+	if err := config.Validate(); err != nil {
+		log.Fatal("invalid config: ", err)
+	}
 
 	return &config
 }
+
+// Validate rejects combinations of options that cannot work together.
+func (c *Config) Validate() error {
+	if c.Ingest.SkipOnStart && c.Storage != StorageElastic {
+		// Memory storage is only ever filled by the server itself, so skipping the load would serve nothing.
+		return errors.New("ingest.skip_on_start needs elastic storage: memory storage is filled on start only")
+	}
+	if r := c.Recommend; r.MaxRadius > 0 && (r.DefaultRadius <= 0 || r.DefaultRadius > r.MaxRadius) {
+		return fmt.Errorf("recommend.default_radius must be positive and not exceed recommend.max_radius (%gm)", r.MaxRadius)
+	}
+	return nil
+}
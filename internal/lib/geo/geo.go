@@ -0,0 +1,121 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EarthRadius is the mean Earth radius in metres, the same value Elasticsearch uses for arc distances.
+const EarthRadius = 6371008.7714
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Distance returns the great-circle distance in metres between two points given in degrees.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := toRadians(lat1), toRadians(lat2)
+	dPhi := phi2 - phi1
+	dLambda := toRadians(lon2 - lon1)
+
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// UnitVector converts a point given in degrees to a vector on the unit sphere.
+// The euclidean (chord) distance between two such vectors grows monotonically
+// with the great-circle distance, so it can be used for exact ordering.
+func UnitVector(lat, lon float64) [3]float64 {
+	phi, lambda := toRadians(lat), toRadians(lon)
+	return [3]float64{
+		math.Cos(phi) * math.Cos(lambda),
+		math.Cos(phi) * math.Sin(lambda),
+		math.Sin(phi),
+	}
+}
+
+// ChordToDistance converts a chord length on the unit sphere to a great-circle distance in metres.
+func ChordToDistance(chord float64) float64 {
+	return 2 * EarthRadius * math.Asin(math.Min(1, chord/2))
+}
+
+// DistanceToChord converts a great-circle distance in metres to a chord length on the unit sphere.
+func DistanceToChord(distance float64) float64 {
+	if distance >= math.Pi*EarthRadius {
+		return 2
+	}
+	return 2 * math.Sin(distance/(2*EarthRadius))
+}
+
+var distanceUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"mi": 1609.344,
+	"yd": 0.9144,
+	"ft": 0.3048,
+}
+
+// ParseDistance parses a distance such as "500m", "2km" or "1.5 mi" into metres.
+// A bare number is taken as metres.
+func ParseDistance(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	num := strings.TrimRightFunc(s, unicode.IsLetter)
+	unit := s[len(num):]
+	num = strings.TrimSpace(num)
+
+	factor := 1.0
+	if unit != "" {
+		f, ok := distanceUnits[unit]
+		if !ok {
+			return 0, fmt.Errorf("unknown distance unit %q", unit)
+		}
+		factor = f
+	}
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid distance %q", s)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("negative distance %q", s)
+	}
+	return value * factor, nil
+}
+
+// Bearing returns the initial compass bearing in degrees, clockwise from north,
+// of the great circle leading from the first point to the second one.
+func Bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := toRadians(lat1), toRadians(lat2)
+	dLambda := toRadians(lon2 - lon1)
+
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	deg := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(deg+360, 360)
+}
+
+var compassPoints = [...]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// CompassPoint names the one of the eight principal winds closest to the bearing.
+func CompassPoint(bearing float64) string {
+	i := int(math.Round(math.Mod(bearing, 360)/45)) % len(compassPoints)
+	if i < 0 {
+		i += len(compassPoints)
+	}
+	return compassPoints[i]
+}
+
+// FormatDistance renders a distance in metres for people: "80 m", "1.2 km", "14 km".
+func FormatDistance(metres float64) string {
+	switch {
+	case metres < 1000:
+		return fmt.Sprintf("%.0f m", metres)
+	case metres < 10000:
+		return fmt.Sprintf("%.1f km", metres/1000)
+	default:
+		return fmt.Sprintf("%.0f km", metres/1000)
+	}
+}
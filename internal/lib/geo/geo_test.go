@@ -0,0 +1,99 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{name: "bare number", s: "750", want: 750},
+		{name: "metres", s: "500m", want: 500},
+		{name: "kilometres", s: "2km", want: 2000},
+		{name: "fraction with space", s: "1.5 km", want: 1500},
+		{name: "upper case", s: "3KM", want: 3000},
+		{name: "miles", s: "1mi", want: 1609.344},
+		{name: "unknown unit", s: "5parsecs", wantErr: true},
+		{name: "negative", s: "-5m", wantErr: true},
+		{name: "empty", s: "", wantErr: true},
+		{name: "not a number", s: "km", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDistance(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDistance() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ParseDistance() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// one degree of longitude on the equator
+	got := Distance(0, 0, 0, 1)
+	want := EarthRadius * math.Pi / 180
+	if math.Abs(got-want) > 1e-6 {
+		t.Errorf("Distance() got = %v, want %v", got, want)
+	}
+	if d := ChordToDistance(DistanceToChord(1234.5)); math.Abs(d-1234.5) > 1e-6 {
+		t.Errorf("ChordToDistance(DistanceToChord()) got = %v, want 1234.5", d)
+	}
+}
+
+func TestBearing(t *testing.T) {
+	tests := []struct {
+		name          string
+		lat2, lon2    float64
+		want          float64
+		wantDirection string
+	}{
+		{name: "north", lat2: 1, lon2: 0, want: 0, wantDirection: "N"},
+		{name: "east", lat2: 0, lon2: 1, want: 90, wantDirection: "E"},
+		{name: "south", lat2: -1, lon2: 0, want: 180, wantDirection: "S"},
+		{name: "west", lat2: 0, lon2: -1, want: 270, wantDirection: "W"},
+		{name: "north-east", lat2: 1, lon2: 1, want: 45, wantDirection: "NE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Bearing(0, 0, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("Bearing() got = %v, want %v", got, tt.want)
+			}
+			if dir := CompassPoint(got); dir != tt.wantDirection {
+				t.Errorf("CompassPoint() got = %v, want %v", dir, tt.wantDirection)
+			}
+		})
+	}
+}
+
+func TestTileKey(t *testing.T) {
+	tests := []struct {
+		name string
+		lat  float64
+		lon  float64
+		zoom int
+		want string
+	}{
+		{name: "whole world", lat: 55.75, lon: 37.61, zoom: 0, want: "0/0/0"},
+		{name: "moscow", lat: 55.7558, lon: 37.6173, zoom: 10, want: "10/619/320"},
+		{name: "southern hemisphere", lat: -33.8688, lon: 151.2093, zoom: 4, want: "4/14/9"},
+		{name: "antimeridian", lat: 0, lon: 180, zoom: 2, want: "2/3/2"},
+		{name: "beyond mercator", lat: 90, lon: -180, zoom: 3, want: "3/0/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TileKey(tt.lat, tt.lon, tt.zoom); got != tt.want {
+				t.Errorf("TileKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxTileZoom is the finest zoom level of map tiles, the highest precision of a geotile_grid aggregation.
+const MaxTileZoom = 29
+
+// maxMercatorLat bounds the Web Mercator projection: points closer to the poles fall into the edge tiles.
+const maxMercatorLat = 85.0511287798066
+
+// Tile returns the column and the row of the Web Mercator map tile containing the point at the zoom level.
+func Tile(lat, lon float64, zoom int) (x, y int) {
+	n := 1 << zoom
+	lat = math.Max(-maxMercatorLat, math.Min(maxMercatorLat, lat))
+	phi := toRadians(lat)
+	fx := (lon + 180) / 360 * float64(n)
+	fy := (1 - math.Asinh(math.Tan(phi))/math.Pi) / 2 * float64(n)
+	clamp := func(v float64) int {
+		return max(0, min(n-1, int(math.Floor(v))))
+	}
+	return clamp(fx), clamp(fy)
+}
+
+// TileKey names the tile containing the point the way geotile_grid buckets are keyed: "zoom/x/y".
+func TileKey(lat, lon float64, zoom int) string {
+	x, y := Tile(lat, lon, zoom)
+	return fmt.Sprintf("%d/%d/%d", zoom, x, y)
+}
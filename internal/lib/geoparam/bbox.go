@@ -0,0 +1,35 @@
+package geoparam
+
+import (
+	"errors"
+	"fmt"
+	"nearestPlaces/internal/entity"
+	"strings"
+)
+
+// ParseBBox parses a bounding box given as "west,south,east,north", the order GeoJSON uses.
+// Each coordinate may be in any format ParseLat and ParseLon accept.
+func ParseBBox(s string) (*entity.BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox must be 'west,south,east,north'")
+	}
+	b := &entity.BBox{}
+	var err error
+	if b.West, err = ParseLon(parts[0]); err != nil {
+		return nil, fmt.Errorf("bbox west: %w", err)
+	}
+	if b.South, err = ParseLat(parts[1]); err != nil {
+		return nil, fmt.Errorf("bbox south: %w", err)
+	}
+	if b.East, err = ParseLon(parts[2]); err != nil {
+		return nil, fmt.Errorf("bbox east: %w", err)
+	}
+	if b.North, err = ParseLat(parts[3]); err != nil {
+		return nil, fmt.Errorf("bbox north: %w", err)
+	}
+	if b.South > b.North {
+		return nil, errors.New("bbox south is above north")
+	}
+	return b, nil
+}
@@ -0,0 +1,51 @@
+package geoparam
+
+import (
+	"errors"
+	"fmt"
+	"nearestPlaces/internal/entity"
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// maxGeohashLen is the longest geohash accepted; 12 characters already pin a point down to centimetres.
+const maxGeohashLen = 12
+
+// ParseGeohash decodes a geohash into the centre of its cell.
+func ParseGeohash(s string) (entity.Point, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return entity.Point{}, errors.New("must not be empty")
+	}
+	if len(s) > maxGeohashLen {
+		return entity.Point{}, fmt.Errorf("must not be longer than %d characters", maxGeohashLen)
+	}
+	lat := [2]float64{-90, 90}
+	lon := [2]float64{-180, 180}
+	even := true
+	for _, c := range s {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		if idx < 0 {
+			return entity.Point{}, fmt.Errorf("%q is not a geohash character", c)
+		}
+		// Bits alternate between longitude and latitude, starting with longitude.
+		for bit := 4; bit >= 0; bit-- {
+			interval := &lat
+			if even {
+				interval = &lon
+			}
+			mid := (interval[0] + interval[1]) / 2
+			if idx&(1<<bit) != 0 {
+				interval[0] = mid
+			} else {
+				interval[1] = mid
+			}
+			even = !even
+		}
+	}
+	return entity.Point{
+		Lat: (lat[0] + lat[1]) / 2,
+		Lon: (lon[0] + lon[1]) / 2,
+	}, nil
+}
@@ -0,0 +1,137 @@
+// Package geoparam parses locations and areas given in request parameters.
+package geoparam
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"nearestPlaces/internal/entity"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseLat parses a latitude in decimal degrees ("-33.8688") or degrees, minutes and seconds
+// ("33°52'7.7\"S", "33 52 7.7 S"). A hemisphere letter may lead or trail the value.
+func ParseLat(s string) (float64, error) {
+	return parseDegrees(s, 90, 'N', 'S')
+}
+
+// ParseLon parses a longitude in the same formats as ParseLat, with E and W for hemispheres.
+func ParseLon(s string) (float64, error) {
+	return parseDegrees(s, 180, 'E', 'W')
+}
+
+// ParseLatLon parses a "lat,lon" pair, each part in any format ParseLat and ParseLon accept.
+func ParseLatLon(s string) (entity.Point, error) {
+	lat, lon, ok := strings.Cut(s, ",")
+	if !ok {
+		return entity.Point{}, errors.New("must be 'lat,lon'")
+	}
+	var p entity.Point
+	var err error
+	if p.Lat, err = ParseLat(lat); err != nil {
+		return entity.Point{}, err
+	}
+	if p.Lon, err = ParseLon(lon); err != nil {
+		return entity.Point{}, err
+	}
+	return p, nil
+}
+
+// FromQuery reads a location from the 'll', 'geohash' or 'lat' and 'lon' query parameters,
+// whichever is present. It returns nil when none is. Errors are *entity.ParamError.
+func FromQuery(q url.Values) (*entity.Point, error) {
+	ll, geohash, latLon := q.Has("ll"), q.Has("geohash"), q.Has("lat") || q.Has("lon")
+	switch {
+	case ll && (geohash || latLon):
+		return nil, &entity.ParamError{Param: "ll", Reason: "must not be combined with 'geohash', 'lat' or 'lon'"}
+	case geohash && latLon:
+		return nil, &entity.ParamError{Param: "geohash", Reason: "must not be combined with 'lat' or 'lon'"}
+	case ll:
+		p, err := ParseLatLon(q.Get("ll"))
+		if err != nil {
+			return nil, &entity.ParamError{Param: "ll", Reason: err.Error()}
+		}
+		return &p, nil
+	case geohash:
+		p, err := ParseGeohash(q.Get("geohash"))
+		if err != nil {
+			return nil, &entity.ParamError{Param: "geohash", Reason: err.Error()}
+		}
+		return &p, nil
+	case latLon:
+		var p entity.Point
+		var err error
+		if p.Lat, err = ParseLat(q.Get("lat")); err != nil {
+			return nil, &entity.ParamError{Param: "lat", Reason: err.Error()}
+		}
+		if p.Lon, err = ParseLon(q.Get("lon")); err != nil {
+			return nil, &entity.ParamError{Param: "lon", Reason: err.Error()}
+		}
+		return &p, nil
+	default:
+		return nil, nil
+	}
+}
+
+// parseDegrees parses an angle of at most limit degrees either way. A hemisphere letter
+// and a minus sign must not be given together.
+func parseDegrees(s string, limit float64, positive, negative rune) (float64, error) {
+	value := strings.TrimSpace(s)
+	if value == "" {
+		return 0, errors.New("must not be empty")
+	}
+	sign := 1.0
+	hemisphere := func(r rune) bool {
+		r = unicode.ToUpper(r)
+		return r == positive || r == negative
+	}
+	if r := []rune(value); hemisphere(r[0]) || hemisphere(r[len(r)-1]) {
+		h := r[len(r)-1]
+		if hemisphere(r[0]) {
+			h, value = r[0], string(r[1:])
+		} else {
+			value = string(r[:len(r)-1])
+		}
+		if unicode.ToUpper(h) == negative {
+			sign = -1
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+			return 0, fmt.Errorf("%q has both a sign and a hemisphere", s)
+		}
+	}
+
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("°º'′\"″:", r)
+	})
+	if len(fields) == 0 || len(fields) > 3 {
+		return 0, fmt.Errorf("%q is not a coordinate", s)
+	}
+	var deg float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("%q is not a coordinate", s)
+		}
+		if i == 0 {
+			if v < 0 {
+				sign, v = -sign, -v
+			}
+			deg = v
+			continue
+		}
+		// Minutes and seconds are unsigned and below 60, and only the last field may have a fraction.
+		if v < 0 || v >= 60 || strings.ContainsAny(f, "+-") || strings.Contains(fields[i-1], ".") {
+			return 0, fmt.Errorf("%q is not a coordinate", s)
+		}
+		deg += v / math.Pow(60, float64(i))
+	}
+	deg *= sign
+	if deg < -limit || deg > limit {
+		return 0, fmt.Errorf("must be between %g and %g", -limit, limit)
+	}
+	return deg, nil
+}
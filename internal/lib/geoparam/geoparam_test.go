@@ -0,0 +1,118 @@
+package geoparam
+
+import (
+	"errors"
+	"math"
+	"nearestPlaces/internal/entity"
+	"net/url"
+	"testing"
+)
+
+func TestParseLat(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{name: "decimal", s: "55.751244", want: 55.751244},
+		{name: "southern hemisphere", s: "-33.8688", want: -33.8688},
+		{name: "trailing hemisphere", s: "33.8688S", want: -33.8688},
+		{name: "leading hemisphere", s: "S 33.8688", want: -33.8688},
+		{name: "dms", s: `33°52'7.68"S`, want: -(33 + 52.0/60 + 7.68/3600)},
+		{name: "dms with primes", s: "55° 45′ 4.5″ N", want: 55 + 45.0/60 + 4.5/3600},
+		{name: "dms with spaces", s: "55 45 4.5 n", want: 55 + 45.0/60 + 4.5/3600},
+		{name: "decimal minutes", s: "55°45.075'N", want: 55 + 45.075/60},
+		{name: "pole", s: "90", want: 90},
+		{name: "out of range", s: "90.5", wantErr: true},
+		{name: "minutes out of range", s: "55 60 0", wantErr: true},
+		{name: "fraction before seconds", s: "55 45.5 10", wantErr: true},
+		{name: "sign and hemisphere", s: "-33.8688S", wantErr: true},
+		{name: "longitude hemisphere", s: "33.8688E", wantErr: true},
+		{name: "empty", s: "", wantErr: true},
+		{name: "not a number", s: "north", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLat(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ParseLat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGeohash(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    entity.Point
+		wantErr bool
+	}{
+		{name: "one character", s: "u", want: entity.Point{Lat: 67.5, Lon: 22.5}},
+		{name: "moscow", s: "ucfv0", want: entity.Point{Lat: 55.7446, Lon: 37.6392}},
+		{name: "upper case", s: "UCFV0", want: entity.Point{Lat: 55.7446, Lon: 37.6392}},
+		{name: "invalid character", s: "ucfva", wantErr: true},
+		{name: "too long", s: "ucfv0ucfv0ucf", wantErr: true},
+		{name: "empty", s: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGeohash(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseGeohash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if math.Abs(got.Lat-tt.want.Lat) > 1e-4 || math.Abs(got.Lon-tt.want.Lon) > 1e-4 {
+				t.Errorf("ParseGeohash() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      *entity.Point
+		wantParam string
+	}{
+		{name: "none", query: "q=pizza"},
+		{name: "lat and lon", query: "lat=-34.6037&lon=-58.3816", want: &entity.Point{Lat: -34.6037, Lon: -58.3816}},
+		{name: "ll", query: "ll=40.7128,-74.006", want: &entity.Point{Lat: 40.7128, Lon: -74.006}},
+		{name: "ll in dms", query: "ll=" + url.QueryEscape(`33°52'7.68"S,151°12'33.48"E`), want: &entity.Point{Lat: -33.8688, Lon: 151.2093}},
+		{name: "geohash", query: "geohash=u", want: &entity.Point{Lat: 67.5, Lon: 22.5}},
+		{name: "lon out of range", query: "lat=10&lon=181", wantParam: "lon"},
+		{name: "lat without lon", query: "lat=10", wantParam: "lon"},
+		{name: "lon without lat", query: "lon=10", wantParam: "lat"},
+		{name: "ll without comma", query: "ll=10", wantParam: "ll"},
+		{name: "ll and lat", query: "ll=10,10&lat=10", wantParam: "ll"},
+		{name: "geohash and lon", query: "geohash=u&lon=10", wantParam: "geohash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := FromQuery(q)
+			var paramErr *entity.ParamError
+			if tt.wantParam != "" {
+				if !errors.As(err, &paramErr) || paramErr.Param != tt.wantParam {
+					t.Fatalf("FromQuery() error = %v, want an error about '%s'", err, tt.wantParam)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromQuery() error = %v", err)
+			}
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("FromQuery() got = %v, want %v", got, tt.want)
+			}
+			if got != nil && (math.Abs(got.Lat-tt.want.Lat) > 1e-4 || math.Abs(got.Lon-tt.want.Lon) > 1e-4) {
+				t.Errorf("FromQuery() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,32 +1,46 @@
 package auth
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
 	"log/slog"
 	"nearestPlaces/internal/infrastructure/tokenGenerator"
 	"nearestPlaces/internal/lib/logger/sl"
+	"nearestPlaces/internal/usecase"
+	"time"
 )
 
 var ErrInternal = errors.New("internal server error")
 
+// AdminClaim is the claim of the tokens that grant access to the admin API.
+const AdminClaim = "admin"
+
 //go:generate go run github.com/vektra/mockery/v2@v2.53.0 --name=TokenGenerator
 type TokenGenerator interface {
 	Generate() (string, error)
+	GenerateWithClaims(ttl time.Duration, claims map[string]interface{}) (string, error)
 }
 
 type UseCase struct {
-	log *slog.Logger
-	tg  TokenGenerator
+	log      *slog.Logger
+	tg       TokenGenerator
+	ttl      time.Duration
+	adminKey string
 }
 
-func New(log *slog.Logger, tg TokenGenerator) *UseCase {
+// New creates the auth use case. Admin tokens living for ttl are issued to whoever presents adminKey;
+// none are issued when it is empty.
+func New(log *slog.Logger, tg TokenGenerator, ttl time.Duration, adminKey string) *UseCase {
 	return &UseCase{
-		log: log,
-		tg:  tg,
+		log:      log,
+		tg:       tg,
+		ttl:      ttl,
+		adminKey: adminKey,
 	}
 }
 
-func (u *UseCase) GetToken() (string, error) {
+func (u *UseCase) GetToken(ctx context.Context) (string, error) {
 	const op = "service.auth.Login"
 	log := u.log.With(
 		slog.String("op", op),
@@ -42,3 +56,22 @@ func (u *UseCase) GetToken() (string, error) {
 	log.Info("token generated", sl.Info(t))
 	return t, nil
 }
+
+// GetAdminToken issues a token carrying the admin claim in exchange for the configured admin key.
+func (u *UseCase) GetAdminToken(ctx context.Context, key string) (string, error) {
+	const op = "service.auth.GetAdminToken"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	if u.adminKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(u.adminKey)) != 1 {
+		log.Warn("admin key does not match")
+		return "", usecase.ErrAdminKey
+	}
+	t, err := u.tg.GenerateWithClaims(u.ttl, map[string]interface{}{AdminClaim: true})
+	if err != nil {
+		log.Error("unable to generate token", sl.Err(err))
+		return "", ErrInternal
+	}
+	log.Info("admin token generated")
+	return t, nil
+}
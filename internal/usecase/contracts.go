@@ -1,24 +1,63 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"nearestPlaces/internal/entity"
+	"net/netip"
 )
 
-var ErrInternal = errors.New("internal server error")
+var ErrGenerationNotFound = fmt.Errorf("index generation %w", entity.ErrNotFound)
+
+var ErrJobNotFound = fmt.Errorf("job %w", entity.ErrNotFound)
+
+// ErrAdminKey is returned for an admin token asked for with a key that does not match the configured one,
+// or when no key is configured.
+var ErrAdminKey = errors.New("admin key does not match")
+
+// StorageError hides the details of a failed storage call from the client and keeps only its kind.
+func StorageError(err error) error {
+	kinds := []error{entity.ErrInvalidArgument, entity.ErrNotFound, entity.ErrUnavailable, entity.ErrTimeout}
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return entity.ErrTimeout
+	}
+	return entity.ErrInternal
+}
 
 type Auther interface {
-	GetToken() (string, error)
+	GetToken(ctx context.Context) (string, error)
+	GetAdminToken(ctx context.Context, key string) (string, error)
 }
 
 type Storer interface {
-	CreateIndexWithMapping() error
-	UploadPlaces() error
+	CreateIndexWithMapping(ctx context.Context) (string, error)
+	UploadPlaces(ctx context.Context, index string) error
+	Reindex(ctx context.Context) error
+	Generations(ctx context.Context) ([]*entity.Generation, error)
+	Rollback(ctx context.Context, to string) (string, error)
+	SubmitDataset(ctx context.Context, upload *DatasetUpload) (*entity.Job, error)
+	Job(ctx context.Context, id string) (*entity.Job, error)
+}
+
+type Locator interface {
+	Locate(ctx context.Context, ip netip.Addr) (*entity.Origin, error)
 }
 
 type Restaurateur interface {
-	GetPage(pageNum int) (*PageInfoDTO, error)
-	GetClosestRestaurants(lat, lon float64) (*PageInfoDTO, error)
+	GetPage(ctx context.Context, pageNum int) (*PageInfoDTO, error)
+	GetPageByCursor(ctx context.Context, cursor string) (*PageInfoDTO, error)
+	GetPageWithin(ctx context.Context, area *entity.Area, pageNum int) (*PageInfoDTO, error)
+	GetClusters(ctx context.Context, bbox *entity.BBox, zoom int) (*ClustersDTO, error)
+	GetClosestRestaurants(ctx context.Context, origin *entity.Origin, limit int, radius float64) (*RecommendationDTO, error)
+	Search(ctx context.Context, text string, near *entity.Point, pageNum int) (*SearchDTO, error)
+	Suggest(ctx context.Context, prefix string, size int) (*SuggestDTO, error)
 }
 
 type PageInfoDTO struct {
@@ -29,4 +68,47 @@ type PageInfoDTO struct {
 	PrevPage int                  `json:"prev_page,omitempty"`
 	NextPage int                  `json:"next_page,omitempty"`
 	LastPage int                  `json:"last_page,omitempty"`
+	// NextCursor and PrevCursor are set only for cursor-based pagination.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type RecommendationDTO struct {
+	Name string `json:"name"`
+	// Origin is the point the places were searched around.
+	Origin *entity.Origin        `json:"origin"`
+	Places []*entity.NearbyPlace `json:"places"`
+}
+
+// ClustersDTO holds either clusters or, at high zoom levels, individual places.
+// Total counts the places in the viewport, of which Places may hold only a part.
+type ClustersDTO struct {
+	Zoom     int                  `json:"zoom"`
+	Total    int                  `json:"total"`
+	Clusters []*entity.Cluster    `json:"clusters,omitempty"`
+	Places   []*entity.Restaurant `json:"places,omitempty"`
+}
+
+type SearchDTO struct {
+	Query    string              `json:"query"`
+	Total    int                 `json:"total"`
+	Hits     []*entity.SearchHit `json:"hits"`
+	Page     int                 `json:"page"`
+	PrevPage int                 `json:"prev_page,omitempty"`
+	NextPage int                 `json:"next_page,omitempty"`
+	LastPage int                 `json:"last_page"`
+}
+
+// DatasetUpload is a data set sent to be loaded. An empty Format is told from the extension of Filename.
+// Mapping, when set, maps the columns of a CSV file as the mapping kept next to a configured data set does.
+type DatasetUpload struct {
+	Filename string
+	Format   string
+	Data     io.Reader
+	Mapping  io.Reader
+}
+
+type SuggestDTO struct {
+	Prefix      string               `json:"prefix"`
+	Suggestions []*entity.Suggestion `json:"suggestions"`
 }
@@ -0,0 +1,57 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+	"net/netip"
+)
+
+var errDisabled = errors.New("no geoip database is configured")
+
+type GeoIP interface {
+	Lookup(ip netip.Addr) (*entity.Origin, error)
+}
+
+type UseCase struct {
+	log   *slog.Logger
+	geoIP GeoIP
+}
+
+// New creates the use case. With a nil database every address is reported as unknown.
+func New(log *slog.Logger, geoIP GeoIP) *UseCase {
+	return &UseCase{
+		log:   log,
+		geoIP: geoIP,
+	}
+}
+
+// Locate approximates where the client with the given address is.
+// Unknown addresses, e.g. private ones, are reported as entity.ErrNotFound.
+func (u *UseCase) Locate(ctx context.Context, ip netip.Addr) (*entity.Origin, error) {
+	const op = "usecase.geolocation.Locate"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.String("ip", ip.String()),
+	)
+	if u.geoIP == nil {
+		return nil, fmt.Errorf("%w: %w", entity.ErrNotFound, errDisabled)
+	}
+	if !ip.IsValid() || ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+		log.Info("address cannot be located")
+		return nil, fmt.Errorf("location of %s: %w", ip, entity.ErrNotFound)
+	}
+	origin, err := u.geoIP.Lookup(ip)
+	if errors.Is(err, entity.ErrNotFound) {
+		log.Info("address is not in the geoip database")
+		return nil, err
+	} else if err != nil {
+		log.Error("failed to look address up", sl.Err(err))
+		return nil, entity.ErrInternal
+	}
+	log.Info("address located", slog.String("city", origin.City), slog.String("country", origin.Country))
+	return origin, nil
+}
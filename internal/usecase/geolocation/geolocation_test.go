@@ -0,0 +1,71 @@
+package geolocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"net/netip"
+	"testing"
+)
+
+// geoIPStub locates 8.8.8.8, does not know 9.9.9.9 and fails for any other address.
+type geoIPStub struct {
+	looked []netip.Addr
+}
+
+func (g *geoIPStub) Lookup(ip netip.Addr) (*entity.Origin, error) {
+	g.looked = append(g.looked, ip)
+	switch ip.String() {
+	case "8.8.8.8":
+		return &entity.Origin{City: "Mountain View", Country: "US"}, nil
+	case "9.9.9.9":
+		return nil, fmt.Errorf("location of %s: %w", ip, entity.ErrNotFound)
+	default:
+		return nil, errors.New("corrupt database")
+	}
+}
+
+func TestUseCase_Locate(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name       string
+		ip         netip.Addr
+		noDB       bool
+		wantCity   string
+		wantErr    error
+		wantLookup bool
+	}{
+		{name: "located", ip: netip.MustParseAddr("8.8.8.8"), wantCity: "Mountain View", wantLookup: true},
+		{name: "no database", ip: netip.MustParseAddr("8.8.8.8"), noDB: true, wantErr: entity.ErrNotFound},
+		{name: "private", ip: netip.MustParseAddr("192.168.1.10"), wantErr: entity.ErrNotFound},
+		{name: "loopback", ip: netip.MustParseAddr("127.0.0.1"), wantErr: entity.ErrNotFound},
+		{name: "loopback v6", ip: netip.MustParseAddr("::1"), wantErr: entity.ErrNotFound},
+		{name: "unspecified", ip: netip.MustParseAddr("0.0.0.0"), wantErr: entity.ErrNotFound},
+		{name: "link local", ip: netip.MustParseAddr("fe80::1"), wantErr: entity.ErrNotFound},
+		{name: "invalid", ip: netip.Addr{}, wantErr: entity.ErrNotFound},
+		{name: "unknown", ip: netip.MustParseAddr("9.9.9.9"), wantErr: entity.ErrNotFound, wantLookup: true},
+		{name: "lookup failure", ip: netip.MustParseAddr("1.1.1.1"), wantErr: entity.ErrInternal, wantLookup: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &geoIPStub{}
+			u := New(log, stub)
+			if tt.noDB {
+				u = New(log, nil)
+			}
+			got, err := u.Locate(context.Background(), tt.ip)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Locate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.City != tt.wantCity {
+				t.Errorf("Locate() city = %q, want %q", got.City, tt.wantCity)
+			}
+			if looked := len(stub.looked) > 0; looked != tt.wantLookup {
+				t.Errorf("Locate() looked the address up = %v, want %v", looked, tt.wantLookup)
+			}
+		})
+	}
+}
@@ -1,59 +1,103 @@
 package restaurants
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/lib/geo"
 	"nearestPlaces/internal/lib/logger/sl"
 	"nearestPlaces/internal/usecase"
+	"strings"
 )
 
+const pageSize = 10
+
+// clusterSubdivision is how many times a map tile is halved to get the cells places are clustered by:
+// a 256px tile is split into 8x8 cells of 32px.
+const clusterSubdivision = 3
+
 type UseCase struct {
 	log     *slog.Logger
+	cfg     *config.Config
 	storage Store
 }
 
-func New(log *slog.Logger, storage Store) *UseCase {
+func New(log *slog.Logger, cfg *config.Config, storage Store) *UseCase {
 	return &UseCase{
 		log:     log,
+		cfg:     cfg,
 		storage: storage,
 	}
 }
 
 type Store interface {
-	GetClosest(lat, lon float64) ([]*entity.Restaurant, error)
-	GetPlaces(limit, offset int) ([]*entity.Restaurant, int, error)
+	GetClosest(ctx context.Context, lat, lon float64, limit int, radius float64) ([]*entity.NearbyPlace, error)
+	GetPlaces(ctx context.Context, limit, offset int) ([]*entity.Restaurant, int, error)
+	GetPlacesByCursor(ctx context.Context, cursor string, limit int) ([]*entity.Restaurant, int, entity.Cursors, error)
+	Search(ctx context.Context, text string, near *entity.Point, limit, offset int) ([]*entity.SearchHit, int, error)
+	GetWithin(ctx context.Context, area *entity.Area, limit, offset int) ([]*entity.Restaurant, int, error)
+	GetClusters(ctx context.Context, bbox *entity.BBox, precision, size int) ([]*entity.Cluster, int, error)
+	Suggest(ctx context.Context, prefix string, size int) ([]*entity.Suggestion, error)
 }
 
-func (u *UseCase) GetClosestRestaurants(lat, lon float64) (*usecase.PageInfoDTO, error) {
+// GetClosestRestaurants returns up to limit places within radius metres of the origin.
+// Zero limit and radius fall back to the configured defaults; values above the configured maximums are rejected.
+// A zero default radius leaves the search unbounded.
+func (u *UseCase) GetClosestRestaurants(ctx context.Context, origin *entity.Origin, limit int, radius float64) (*usecase.RecommendationDTO, error) {
 	const op = "usecase.restaurants.GetClosestRestaurants"
 	log := u.log.With(
 		slog.String("op", op),
 	)
-	places, err := u.storage.GetClosest(lat, lon)
+	opts := u.cfg.Recommend
+	if limit == 0 {
+		limit = opts.DefaultLimit
+	}
+	if limit > opts.MaxLimit {
+		log.Error("limit is too large", slog.Int("limit", limit))
+		return nil, &entity.ParamError{Param: "limit", Reason: fmt.Sprintf("must not exceed %d", opts.MaxLimit)}
+	}
+	if radius == 0 {
+		radius = opts.DefaultRadius
+	}
+	if opts.MaxRadius > 0 && radius > opts.MaxRadius {
+		log.Error("radius is too large", slog.Float64("radius", radius))
+		return nil, &entity.ParamError{Param: "radius", Reason: fmt.Sprintf("must not exceed %gm", opts.MaxRadius)}
+	}
+
+	lat, lon := origin.Lat, origin.Lon
+	places, err := u.storage.GetClosest(ctx, lat, lon, limit, radius)
 	if err != nil {
 		log.Error("failed to get closest restaurants", sl.Err(err))
-		return nil, usecase.ErrInternal
+		return nil, usecase.StorageError(err)
 	}
 	log.Info("closest restaurants received")
 
-	result := &usecase.PageInfoDTO{
+	for _, p := range places {
+		p.DistanceText = geo.FormatDistance(p.Distance)
+		p.Bearing = geo.Bearing(lat, lon, p.Location.Lat, p.Location.Lon)
+		p.Direction = geo.CompassPoint(p.Bearing)
+	}
+	result := &usecase.RecommendationDTO{
 		Name:   "Recommendation",
+		Origin: origin,
 		Places: places,
 	}
 	return result, nil
 }
 
-func (u *UseCase) GetPage(pageNum int) (*usecase.PageInfoDTO, error) {
+func (u *UseCase) GetPage(ctx context.Context, pageNum int) (*usecase.PageInfoDTO, error) {
 	const op = "usecase.restaurants.GetPages"
 	log := u.log.With(
 		slog.String("op", op),
 	)
-	limit := 10
-	offset := (pageNum - 1) * limit
-	places, total, err := u.storage.GetPlaces(limit, offset)
+	offset := (pageNum - 1) * pageSize
+	places, total, err := u.storage.GetPlaces(ctx, pageSize, offset)
 	if err != nil {
 		log.Error("failed to get places: ", sl.Err(err))
-		return nil, usecase.ErrInternal
+		return nil, usecase.StorageError(err)
 	}
 	log.Info("page received from storage")
 
@@ -64,7 +108,173 @@ func (u *UseCase) GetPage(pageNum int) (*usecase.PageInfoDTO, error) {
 		Page:     pageNum,
 		PrevPage: pageNum - 1,
 		NextPage: pageNum + 1,
-		LastPage: total/limit + 1,
+		LastPage: total/pageSize + 1,
+	}
+	return result, nil
+}
+
+func (u *UseCase) GetPageByCursor(ctx context.Context, cursor string) (*usecase.PageInfoDTO, error) {
+	const op = "usecase.restaurants.GetPageByCursor"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	places, total, cursors, err := u.storage.GetPlacesByCursor(ctx, cursor, pageSize)
+	if errors.Is(err, entity.ErrInvalidCursor) {
+		log.Error("invalid cursor", sl.Err(err))
+		return nil, entity.ErrInvalidCursor
+	} else if err != nil {
+		log.Error("failed to get places: ", sl.Err(err))
+		return nil, usecase.StorageError(err)
+	}
+	log.Info("page received from storage")
+
+	result := &usecase.PageInfoDTO{
+		Name:       "Places",
+		Total:      total,
+		Places:     places,
+		NextCursor: cursors.Next,
+		PrevCursor: cursors.Prev,
+	}
+	return result, nil
+}
+
+// GetPageWithin returns a page of the places lying inside a bounding box or polygons.
+func (u *UseCase) GetPageWithin(ctx context.Context, area *entity.Area, pageNum int) (*usecase.PageInfoDTO, error) {
+	const op = "usecase.restaurants.GetPageWithin"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	if area.BBox == nil && len(area.Polygons) == 0 {
+		log.Error("empty area")
+		return nil, fmt.Errorf("%w: either 'bbox' or a polygon is required", entity.ErrInvalidArgument)
+	}
+	offset := (pageNum - 1) * pageSize
+	places, total, err := u.storage.GetWithin(ctx, area, pageSize, offset)
+	if err != nil {
+		log.Error("failed to get places: ", sl.Err(err))
+		return nil, usecase.StorageError(err)
+	}
+	log.Info("page received from storage", slog.Int("total", total))
+
+	lastPage := max(1, (total+pageSize-1)/pageSize)
+	result := &usecase.PageInfoDTO{
+		Name:     "Places",
+		Total:    total,
+		Places:   places,
+		Page:     pageNum,
+		PrevPage: pageNum - 1,
+		LastPage: lastPage,
+	}
+	if pageNum < lastPage {
+		result.NextPage = pageNum + 1
+	}
+	return result, nil
+}
+
+// GetClusters groups the places inside the box for a map shown at the zoom level. From the configured
+// zoom level on, places are returned one by one instead. A nil box stands for the whole world.
+func (u *UseCase) GetClusters(ctx context.Context, bbox *entity.BBox, zoom int) (*usecase.ClustersDTO, error) {
+	const op = "usecase.restaurants.GetClusters"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	opts := u.cfg.Clusters
+	if zoom < 0 || zoom > geo.MaxTileZoom {
+		log.Error("zoom is out of range", slog.Int("zoom", zoom))
+		return nil, &entity.ParamError{Param: "zoom", Reason: fmt.Sprintf("must be between 0 and %d", geo.MaxTileZoom)}
+	}
+	if bbox == nil {
+		bbox = &entity.BBox{West: -180, South: -90, East: 180, North: 90}
+	}
+	result := &usecase.ClustersDTO{Zoom: zoom}
+
+	if zoom >= opts.PlacesZoom {
+		places, total, err := u.storage.GetWithin(ctx, &entity.Area{BBox: bbox}, opts.MaxPlaces, 0)
+		if err != nil {
+			log.Error("failed to get places: ", sl.Err(err))
+			return nil, usecase.StorageError(err)
+		}
+		log.Info("places received from storage", slog.Int("total", total))
+		result.Total = total
+		result.Places = places
+		return result, nil
+	}
+
+	precision := min(zoom+clusterSubdivision, geo.MaxTileZoom)
+	clusters, total, err := u.storage.GetClusters(ctx, bbox, precision, opts.MaxClusters)
+	if err != nil {
+		log.Error("failed to get clusters: ", sl.Err(err))
+		return nil, usecase.StorageError(err)
+	}
+	log.Info("clusters received from storage", slog.Int("count", len(clusters)), slog.Int("total", total))
+	result.Total = total
+	result.Clusters = clusters
+	return result, nil
+}
+
+// Search finds places by name or address, tolerating typos. When near is set,
+// equally relevant places closer to it come first.
+func (u *UseCase) Search(ctx context.Context, text string, near *entity.Point, pageNum int) (*usecase.SearchDTO, error) {
+	const op = "usecase.restaurants.Search"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	if strings.TrimSpace(text) == "" {
+		log.Error("empty query")
+		return nil, &entity.ParamError{Param: "q", Reason: "must not be empty"}
+	}
+	offset := (pageNum - 1) * pageSize
+	hits, total, err := u.storage.Search(ctx, text, near, pageSize, offset)
+	if err != nil {
+		log.Error("failed to search places: ", sl.Err(err))
+		return nil, usecase.StorageError(err)
+	}
+	log.Info("search results received from storage", slog.Int("total", total))
+
+	lastPage := max(1, (total+pageSize-1)/pageSize)
+	result := &usecase.SearchDTO{
+		Query:    text,
+		Total:    total,
+		Hits:     hits,
+		Page:     pageNum,
+		PrevPage: pageNum - 1,
+		LastPage: lastPage,
+	}
+	if pageNum < lastPage {
+		result.NextPage = pageNum + 1
+	}
+	return result, nil
+}
+
+// Suggest completes a place name or a street being typed. Zero size falls back to the configured default.
+func (u *UseCase) Suggest(ctx context.Context, prefix string, size int) (*usecase.SuggestDTO, error) {
+	const op = "usecase.restaurants.Suggest"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	opts := u.cfg.Suggest
+	if strings.TrimSpace(prefix) == "" {
+		log.Error("empty prefix")
+		return nil, &entity.ParamError{Param: "prefix", Reason: "must not be empty"}
+	}
+	if size == 0 {
+		size = opts.DefaultSize
+	}
+	if size > opts.MaxSize {
+		log.Error("size is too large", slog.Int("size", size))
+		return nil, &entity.ParamError{Param: "size", Reason: fmt.Sprintf("must not exceed %d", opts.MaxSize)}
+	}
+
+	suggestions, err := u.storage.Suggest(ctx, prefix, size)
+	if err != nil {
+		log.Error("failed to get suggestions", sl.Err(err))
+		return nil, usecase.StorageError(err)
+	}
+	log.Info("suggestions received from storage", slog.Int("count", len(suggestions)))
+
+	result := &usecase.SuggestDTO{
+		Prefix:      prefix,
+		Suggestions: suggestions,
 	}
 	return result, nil
 }
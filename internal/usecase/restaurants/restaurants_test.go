@@ -0,0 +1,62 @@
+package restaurants
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/config"
+	"testing"
+)
+
+// closestStub records the radius GetClosest was asked for and finds nothing.
+type closestStub struct {
+	Store
+	radius float64
+}
+
+func (s *closestStub) GetClosest(ctx context.Context, lat, lon float64, limit int, radius float64) ([]*entity.NearbyPlace, error) {
+	s.radius = radius
+	return nil, nil
+}
+
+func TestUseCase_GetClosestRestaurants_radius(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name       string
+		opts       config.Recommend
+		radius     float64
+		wantRadius float64
+		wantErr    bool
+	}{
+		{"omitted uses default", config.Recommend{DefaultRadius: 1000, MaxRadius: 5000}, 0, 1000, false},
+		{"requested", config.Recommend{DefaultRadius: 1000, MaxRadius: 5000}, 2500, 2500, false},
+		{"at the cap", config.Recommend{DefaultRadius: 1000, MaxRadius: 5000}, 5000, 5000, false},
+		{"above the cap", config.Recommend{DefaultRadius: 1000, MaxRadius: 5000}, 5001, 0, true},
+		{"omitted without default or cap is unbounded", config.Recommend{}, 0, 0, false},
+		{"no cap", config.Recommend{DefaultRadius: 1000}, 100000, 100000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.DefaultLimit, tt.opts.MaxLimit = 3, 50
+			storage := &closestStub{}
+			u := New(log, &config.Config{Recommend: tt.opts}, storage)
+
+			_, err := u.GetClosestRestaurants(context.Background(), &entity.Origin{}, 0, tt.radius)
+			var paramErr *entity.ParamError
+			if tt.wantErr {
+				if !errors.As(err, &paramErr) || paramErr.Param != "radius" {
+					t.Fatalf("GetClosestRestaurants() error = %v, want a radius ParamError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetClosestRestaurants() error = %v", err)
+			}
+			if storage.radius != tt.wantRadius {
+				t.Errorf("GetClosest() radius = %g, want %g", storage.radius, tt.wantRadius)
+			}
+		})
+	}
+}
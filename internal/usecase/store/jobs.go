@@ -0,0 +1,393 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/lib/logger/sl"
+	"nearestPlaces/internal/usecase"
+	"sync"
+	"time"
+)
+
+// jobRejections is how many of the rows a job rejects it keeps to be shown.
+const jobRejections = 10
+
+// keptJobs is how many finished jobs are kept to be reported on; older ones are forgotten.
+const keptJobs = 100
+
+// uploadFormats are the formats a data set can be uploaded in.
+var uploadFormats = map[string]bool{
+	"csv":     true,
+	"geojson": true,
+}
+
+// dataset is a data set to load and the ingestion job loading it, nil for the configured data set.
+type dataset struct {
+	path   string
+	format string
+	job    *job
+}
+
+func (u *UseCase) configured() *dataset {
+	return &dataset{
+		path:   u.cfg.DataPath,
+		format: u.cfg.DataFormat,
+	}
+}
+
+// job is an ingestion job, updated by the load running it while requests read it.
+// Its methods do nothing on a nil job, so that loads of the configured data set need not check.
+type job struct {
+	mu   sync.Mutex
+	info entity.Job
+	// path is the data set the job loads: an upload, kept while the job waits and runs, or the configured one.
+	path string
+}
+
+func (j *job) snapshot() *entity.Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := j.info
+	info.Rejections = append([]entity.Rejection(nil), j.info.Rejections...)
+	return &info
+}
+
+func (j *job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.info.State = entity.JobRunning
+	j.info.StartedAt = &now
+}
+
+// finish records how the job ended. A failed job keeps the summary of the sync it ran, if any.
+func (j *job) finish(summary *entity.SyncSummary, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.info.FinishedAt = &now
+	j.info.Sync = summary
+	if err != nil {
+		j.info.State = entity.JobFailed
+		j.info.Error = err.Error()
+		return
+	}
+	j.info.State = entity.JobSucceeded
+}
+
+func (j *job) loading(index string, size int64) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.Index = index
+	j.info.Progress.BytesTotal = size
+}
+
+func (j *job) progress(p *progress, rejected int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.Progress.Rows = p.rows
+	j.info.Progress.BytesRead = p.bytes
+	j.info.Progress.Rejected = rejected
+}
+
+func (j *job) reject(rejection entity.Rejection) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.info.Rejections) < jobRejections {
+		j.info.Rejections = append(j.info.Rejections, rejection)
+	}
+}
+
+func (j *job) reported(path string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.Report = path
+}
+
+// jobs runs ingestion jobs one at a time, in the order they were submitted,
+// as two loads switching the alias at once would race. Queued and running jobs are kept,
+// finished ones only up to the given number, the oldest being forgotten first.
+type jobs struct {
+	mu      sync.Mutex
+	byID    map[string]*job
+	queue   []*job
+	running bool
+	// finished holds the IDs of the finished jobs that are kept, in the order they finished.
+	finished []string
+	kept     int
+}
+
+func newJobs(kept int) *jobs {
+	return &jobs{
+		byID: make(map[string]*job),
+		kept: kept,
+	}
+}
+
+// push queues the job and tells whether a worker has to be started to run the queue.
+func (js *jobs) push(j *job) bool {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.byID[j.info.ID] = j
+	js.queue = append(js.queue, j)
+	start := !js.running
+	js.running = true
+	return start
+}
+
+// next takes the next job off the queue, or returns nil once the queue is empty and the worker is to stop.
+func (js *jobs) next() *job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if len(js.queue) == 0 {
+		js.running = false
+		return nil
+	}
+	j := js.queue[0]
+	js.queue = js.queue[1:]
+	return j
+}
+
+// done keeps the job as finished, forgetting the oldest finished jobs beyond the number kept.
+func (js *jobs) done(j *job) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.finished = append(js.finished, j.info.ID)
+	for len(js.finished) > js.kept {
+		delete(js.byID, js.finished[0])
+		js.finished = js.finished[1:]
+	}
+}
+
+func (js *jobs) get(id string) *job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	return js.byID[id]
+}
+
+// SubmitDataset keeps the uploaded data set and queues a job loading it in the configured ingest mode.
+// Only CSV and GeoJSON data sets are accepted.
+func (u *UseCase) SubmitDataset(ctx context.Context, upload *usecase.DatasetUpload) (*entity.Job, error) {
+	const op = "usecase.store.SubmitDataset"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.String("filename", upload.Filename),
+	)
+	format, err := u.importer.Format(upload.Filename, upload.Format)
+	if err != nil {
+		log.Error("failed to tell data format", sl.Err(err))
+		return nil, err
+	}
+	if !uploadFormats[format] {
+		log.Error("data format cannot be uploaded", slog.String("format", format))
+		return nil, fmt.Errorf("%w: data sets are uploaded as csv or geojson, not %s", entity.ErrInvalidArgument, format)
+	}
+	id, err := newJobID()
+	if err != nil {
+		log.Error("failed to create job ID", sl.Err(err))
+		return nil, err
+	}
+	path, err := u.uploads.Save(id, upload)
+	if err != nil {
+		log.Error("failed to save upload", sl.Err(err))
+		return nil, err
+	}
+
+	j := u.newJob(id, entity.TriggerUpload, upload.Filename, format, u.mode())
+	j.path = path
+	u.enqueue(j)
+	log.Info("job queued", slog.String("job", id), slog.String("format", format), slog.String("mode", j.info.Mode))
+	return j.snapshot(), nil
+}
+
+// SubmitReload queues a job loading the configured data set again once it has changed on disk.
+// A changed schema calls for a new generation, so the job reloads even in sync mode.
+// The data set and the schema are checked first, so that a file that is broken or only half written
+// is turned down without a job being run; the current generation keeps serving queries either way.
+func (u *UseCase) SubmitReload(ctx context.Context, schemaChanged bool) (*entity.Job, error) {
+	const op = "usecase.store.SubmitReload"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.Bool("schema_changed", schemaChanged),
+	)
+	if err := u.checkSources(ctx); err != nil {
+		log.Error("data set turned down", sl.Err(err))
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		log.Error("failed to create job ID", sl.Err(err))
+		return nil, err
+	}
+	mode := u.mode()
+	if schemaChanged {
+		mode = config.IngestReload
+	}
+	j := u.newJob(id, entity.TriggerWatch, u.cfg.DataPath, u.cfg.DataFormat, mode)
+	j.path = u.cfg.DataPath
+	u.enqueue(j)
+	log.Info("job queued", slog.String("job", id), slog.String("mode", mode))
+	return j.snapshot(), nil
+}
+
+// checkSources checks that the schema is valid JSON and that the data set can be read,
+// which includes its header and mapping for a CSV file, without loading it.
+func (u *UseCase) checkSources(ctx context.Context) error {
+	if err := u.checkSchema(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	records, _, err := u.importer.Import(ctx, u.cfg.DataPath, u.cfg.DataFormat)
+	if err != nil {
+		return err
+	}
+	rec, ok := <-records
+	if !ok {
+		return fmt.Errorf("%w: data set %s holds no places", entity.ErrInvalidArgument, u.cfg.DataPath)
+	}
+	if errors.Is(rec.Err, entity.ErrSourceBroken) {
+		return fmt.Errorf("line %d: %w", rec.Line, rec.Err)
+	}
+	return nil
+}
+
+func (u *UseCase) checkSchema() error {
+	mappings, err := u.schemaReader.ReadMappings(u.cfg.SchemaPath)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(mappings) {
+		return fmt.Errorf("%w: schema %s is not valid JSON", entity.ErrInvalidArgument, u.cfg.SchemaPath)
+	}
+	return nil
+}
+
+// mode is the ingest mode jobs run in unless told otherwise.
+func (u *UseCase) mode() string {
+	if u.cfg.Ingest.Mode == config.IngestSync {
+		return config.IngestSync
+	}
+	return config.IngestReload
+}
+
+func (u *UseCase) newJob(id, trigger, filename, format, mode string) *job {
+	return &job{
+		info: entity.Job{
+			ID:        id,
+			State:     entity.JobQueued,
+			Trigger:   trigger,
+			Filename:  filename,
+			Format:    format,
+			Mode:      mode,
+			CreatedAt: time.Now(),
+		},
+	}
+}
+
+func (u *UseCase) enqueue(j *job) {
+	if u.jobs.push(j) {
+		u.workers.Add(1)
+		go u.runJobs()
+	}
+}
+
+// Job returns the state of the ingestion job with the given ID.
+func (u *UseCase) Job(ctx context.Context, id string) (*entity.Job, error) {
+	j := u.jobs.get(id)
+	if j == nil {
+		return nil, usecase.ErrJobNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// runJobs runs the queued jobs until there are none left. Jobs are not bound to the request
+// that submitted them, so they run within the lifetime of the use case.
+func (u *UseCase) runJobs() {
+	defer u.workers.Done()
+	for j := u.jobs.next(); j != nil; j = u.jobs.next() {
+		u.runJob(u.ctx, j)
+	}
+}
+
+// Shutdown cancels the running job and fails the queued ones, then waits until the generation
+// the job was filling is dropped and the uploads are removed, or until ctx is done.
+func (u *UseCase) Shutdown(ctx context.Context) error {
+	u.stop()
+	done := make(chan struct{})
+	go func() {
+		u.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (u *UseCase) runJob(ctx context.Context, j *job) {
+	const op = "usecase.store.runJob"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.String("job", j.info.ID),
+	)
+	j.start()
+	log.Info("job started")
+
+	ds := &dataset{path: j.path, format: j.info.Format, job: j}
+	var summary *entity.SyncSummary
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		// the server is shutting down; the job is not worth starting
+		err = ctx.Err()
+	case j.info.Mode == config.IngestSync:
+		summary, err = u.syncDataset(ctx, ds)
+	default:
+		err = u.reindex(ctx, ds)
+	}
+	j.finish(summary, err)
+	u.jobs.done(j)
+	if err != nil {
+		log.Error("job failed", sl.Err(err))
+	} else {
+		log.Info("job succeeded")
+	}
+
+	if j.info.Trigger != entity.TriggerUpload {
+		return
+	}
+	if err := u.uploads.Remove(j.path); err != nil {
+		log.Error("failed to remove upload", sl.Err(err))
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
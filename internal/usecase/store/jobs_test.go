@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/infrastructure/repository/memory"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/usecase"
+	"strings"
+	"testing"
+	"time"
+)
+
+type uploadsStub struct {
+	removed chan string
+}
+
+func (u *uploadsStub) Save(id string, upload *usecase.DatasetUpload) (string, error) {
+	return id + ".csv", nil
+}
+
+func (u *uploadsStub) Remove(path string) error {
+	u.removed <- path
+	return nil
+}
+
+func TestUseCase_SubmitDataset(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Ingest: config.Ingest{Buffer: 4, MaxRejectRate: 0.05}}
+	data := &importerStub{places: []*entity.Restaurant{place("1", "A"), place("2", "B")}}
+	uploads := &uploadsStub{removed: make(chan string, 1)}
+	u := New(log, cfg, schemaStub{}, data, memory.New(log, "places"), nil, uploads)
+
+	_, err := u.SubmitDataset(context.Background(), &usecase.DatasetUpload{Filename: "places.ndjson", Format: "ndjson", Data: strings.NewReader("")})
+	if !errors.Is(err, entity.ErrInvalidArgument) {
+		t.Errorf("SubmitDataset() of ndjson error = %v, want %v", err, entity.ErrInvalidArgument)
+	}
+
+	queued, err := u.SubmitDataset(context.Background(), &usecase.DatasetUpload{Filename: "places.csv", Format: "csv", Data: strings.NewReader("")})
+	if err != nil {
+		t.Fatalf("SubmitDataset() error = %v", err)
+	}
+	if queued.State != entity.JobQueued || queued.Mode != config.IngestReload {
+		t.Errorf("SubmitDataset() job = %+v, want a queued reload", queued)
+	}
+	select {
+	case path := <-uploads.removed:
+		if path != queued.ID+".csv" {
+			t.Errorf("removed upload = %s, want %s", path, queued.ID+".csv")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not finish")
+	}
+
+	job, err := u.Job(context.Background(), queued.ID)
+	if err != nil {
+		t.Fatalf("Job() error = %v", err)
+	}
+	if job.State != entity.JobSucceeded || job.Index == "" || job.Progress.Rows != 2 || job.FinishedAt == nil {
+		t.Errorf("Job() = %+v, want a succeeded job that loaded 2 rows", job)
+	}
+	if _, err := u.Job(context.Background(), "missing"); !errors.Is(err, usecase.ErrJobNotFound) {
+		t.Errorf("Job() of a missing job error = %v, want %v", err, usecase.ErrJobNotFound)
+	}
+}
+
+func TestJobs_done(t *testing.T) {
+	js := newJobs(2)
+	for _, id := range []string{"1", "2", "3"} {
+		j := &job{info: entity.Job{ID: id}}
+		js.push(j)
+		js.done(js.next())
+	}
+	for id, want := range map[string]bool{"1": false, "2": true, "3": true} {
+		if got := js.get(id) != nil; got != want {
+			t.Errorf("get(%s) found = %v, want %v", id, got, want)
+		}
+	}
+}
+
+// blockingImporter streams no places until the load is cancelled.
+type blockingImporter struct {
+	importerStub
+}
+
+func (i *blockingImporter) Import(ctx context.Context, filename, format string) (<-chan entity.Record, int64, error) {
+	out := make(chan entity.Record)
+	go func() {
+		<-ctx.Done()
+		close(out)
+	}()
+	return out, 0, nil
+}
+
+func TestUseCase_Shutdown(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Ingest: config.Ingest{Buffer: 4, MaxRejectRate: 0.05}}
+	storage := memory.New(log, "places")
+	uploads := &uploadsStub{removed: make(chan string, 1)}
+	u := New(log, cfg, schemaStub{}, &blockingImporter{}, storage, nil, uploads)
+
+	queued, err := u.SubmitDataset(context.Background(), &usecase.DatasetUpload{Filename: "places.csv", Format: "csv", Data: strings.NewReader("")})
+	if err != nil {
+		t.Fatalf("SubmitDataset() error = %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		job, err := u.Job(context.Background(), queued.ID)
+		if err != nil {
+			t.Fatalf("Job() error = %v", err)
+		}
+		if job.State == entity.JobRunning && job.Index != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job() = %+v, want a running job", job)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := u.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case path := <-uploads.removed:
+		if path != queued.ID+".csv" {
+			t.Errorf("removed upload = %s, want %s", path, queued.ID+".csv")
+		}
+	default:
+		t.Error("Shutdown() returned before the upload was removed")
+	}
+	job, err := u.Job(context.Background(), queued.ID)
+	if err != nil || job.State != entity.JobFailed {
+		t.Errorf("Job() after shutdown = %+v, error = %v, want a failed job", job, err)
+	}
+	if generations, err := storage.Generations(context.Background()); err != nil || len(generations) != 0 {
+		t.Errorf("Generations() after shutdown = %v, error = %v, want the unfinished one dropped", generations, err)
+	}
+}
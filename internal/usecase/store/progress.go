@@ -0,0 +1,44 @@
+package store
+
+import (
+	"log/slog"
+	"time"
+)
+
+// progress tracks how far a load has got through its source.
+type progress struct {
+	start time.Time
+	size  int64
+	rows  int
+	bytes int64
+}
+
+func newProgress(size int64) *progress {
+	return &progress{
+		start: time.Now(),
+		size:  size,
+	}
+}
+
+func (p *progress) add(offset int64) {
+	p.rows++
+	p.bytes = offset
+}
+
+// attrs describes the progress for the log: rows per second, bytes read and, while the source
+// is not through yet, the time left at the current rate.
+func (p *progress) attrs() []any {
+	elapsed := time.Since(p.start)
+	attrs := []any{
+		slog.Int("rows", p.rows),
+		slog.Int("rows_per_sec", int(float64(p.rows)/max(elapsed.Seconds(), 1e-9))),
+		slog.Int64("bytes_read", p.bytes),
+		slog.Int64("bytes_total", p.size),
+		slog.String("elapsed", elapsed.Round(time.Millisecond).String()),
+	}
+	if p.bytes > 0 && p.bytes < p.size {
+		eta := time.Duration(float64(elapsed) * float64(p.size-p.bytes) / float64(p.bytes))
+		attrs = append(attrs, slog.String("eta", eta.Round(time.Second).String()))
+	}
+	return attrs
+}
@@ -1,11 +1,16 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"nearestPlaces/internal/entity"
 	"nearestPlaces/internal/lib/config"
 	"nearestPlaces/internal/lib/logger/sl"
+	"nearestPlaces/internal/usecase"
+	"sync"
+	"time"
 )
 
 type SchemaReader interface {
@@ -13,33 +18,78 @@ type SchemaReader interface {
 }
 
 type Storage interface {
-	CreateIndex(mappings []byte) error
-	SaveData(data []*entity.Restaurant) error
+	CreateIndex(ctx context.Context, mappings []byte) (string, error)
+	SaveData(ctx context.Context, index string, places <-chan *entity.Restaurant) error
+	Hashes(ctx context.Context, index string) (map[string]string, error)
+	ApplyChanges(ctx context.Context, index string, changes []entity.Change) error
+	CountDocuments(ctx context.Context, index string) (int, error)
+	Generations(ctx context.Context) ([]*entity.Generation, error)
+	SwitchAlias(ctx context.Context, index string) error
+	DeleteIndex(ctx context.Context, index string) error
 }
 
-type CSVParser interface {
-	ParseCSV(filename string) ([]*entity.Restaurant, error)
+// Importer streams the places of a data set. An empty format is told from the file extension.
+type Importer interface {
+	Import(ctx context.Context, filename, format string) (<-chan entity.Record, int64, error)
+	Format(filename, format string) (string, error)
 }
 
+// Uploads keeps uploaded data sets on disk until the jobs loading them are over.
+type Uploads interface {
+	Save(id string, upload *usecase.DatasetUpload) (string, error)
+	Remove(path string) error
+}
+
+type RejectionReport interface {
+	Add(rejection entity.Rejection) error
+	Path() string
+	Close() error
+}
+
+// ReportOpener creates the report of the rows rejected by a load into the given generation.
+type ReportOpener func(index string) (RejectionReport, error)
+
 type UseCase struct {
 	log          *slog.Logger
 	cfg          *config.Config
 	schemaReader SchemaReader
-	csvParser    CSVParser
+	importer     Importer
 	storage      Storage
+	openReport   ReportOpener
+	uploads      Uploads
+	jobs         *jobs
+	// ctx is the lifetime of the jobs, cancelled by Shutdown; workers counts the goroutines running them.
+	ctx     context.Context
+	stop    context.CancelFunc
+	workers sync.WaitGroup
 }
 
-func New(log *slog.Logger, cfg *config.Config, reader SchemaReader, parser CSVParser, storage Storage) *UseCase {
+func New(log *slog.Logger, cfg *config.Config, reader SchemaReader, importer Importer, storage Storage, openReport ReportOpener, uploads Uploads) *UseCase {
+	ctx, stop := context.WithCancel(context.Background())
 	return &UseCase{
 		log:          log,
 		cfg:          cfg,
 		schemaReader: reader,
-		csvParser:    parser,
+		importer:     importer,
 		storage:      storage,
+		openReport:   openReport,
+		uploads:      uploads,
+		jobs:         newJobs(keptJobs),
+		ctx:          ctx,
+		stop:         stop,
 	}
 }
 
-func (u *UseCase) CreateIndexWithMapping() error {
+// cleanupTimeout bounds the cleanup after a failed load, which is done even when the load was cancelled.
+const cleanupTimeout = 30 * time.Second
+
+// cleanupContext is a context for cleaning up after a load that outlives the cancellation of its context.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// CreateIndexWithMapping creates a new generation of the index and returns its name.
+func (u *UseCase) CreateIndexWithMapping(ctx context.Context) (string, error) {
 	const op = "usecase.store.createIndexWithMapping"
 	log := u.log.With(
 		slog.String("op", op),
@@ -47,36 +97,402 @@ func (u *UseCase) CreateIndexWithMapping() error {
 	mappings, err := u.schemaReader.ReadMappings(u.cfg.SchemaPath)
 	if err != nil {
 		log.Error("failed to read mappings: ", sl.Err(err))
-		return err
+		return "", err
 	}
 	logMsg := fmt.Sprintf("successfully read mappings from %s", u.cfg.SchemaPath)
 	log.Info(logMsg)
 
-	err = u.storage.CreateIndex(mappings)
+	index, err := u.storage.CreateIndex(ctx, mappings)
 	if err != nil {
 		log.Error("failed to create index: ", sl.Err(err))
+		return "", err
+	}
+	log.Info("successfully created index", slog.String("index", index))
+	return index, nil
+}
+
+// UploadPlaces fills the given generation, checks that every place made it into the index
+// and then makes the generation current. A generation that fails to load is dropped,
+// so the current one keeps serving queries.
+func (u *UseCase) UploadPlaces(ctx context.Context, index string) error {
+	return u.uploadPlaces(ctx, index, u.configured())
+}
+
+func (u *UseCase) uploadPlaces(ctx context.Context, index string, ds *dataset) error {
+	const op = "usecase.store.fillIndex"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.String("index", index),
+	)
+	err := u.fill(ctx, index, ds)
+	if err != nil {
+		log.Error("failed to fill index: ", sl.Err(err))
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		if err := u.storage.DeleteIndex(ctx, index); err != nil {
+			log.Error("failed to drop unfinished index: ", sl.Err(err))
+		}
+		return err
+	}
+
+	err = u.storage.SwitchAlias(ctx, index)
+	if err != nil {
+		log.Error("failed to switch alias: ", sl.Err(err))
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		if err := u.storage.DeleteIndex(ctx, index); err != nil {
+			log.Error("failed to drop unfinished index: ", sl.Err(err))
+		}
 		return err
 	}
-	log.Info("successfully created index")
+	log.Info("index is now current")
+
+	u.prune(ctx)
 	return nil
 }
 
-func (u *UseCase) UploadPlaces() error {
-	const op = "usecase.store.fillIndex"
+// fill streams the data set into the index: rows are parsed, validated and indexed concurrently,
+// so that only the rows in flight are held in memory. Invalid rows are written to the rejection report;
+// the first one stops the load unless ingest.lenient is set.
+func (u *UseCase) fill(ctx context.Context, index string, ds *dataset) error {
+	const op = "usecase.store.fill"
 	log := u.log.With(
 		slog.String("op", op),
+		slog.String("index", index),
 	)
-	data, err := u.csvParser.ParseCSV(u.cfg.DataPath)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	records, size, err := u.importer.Import(ctx, ds.path, ds.format)
 	if err != nil {
-		log.Error("failed to parse data: ", sl.Err(err))
+		log.Error("failed to open data: ", sl.Err(err))
 		return err
 	}
-	log.Info("data parsed successfully")
+	log.Info("loading data", slog.String("path", ds.path), slog.String("format", ds.format), slog.Int64("bytes", size),
+		slog.Bool("lenient", u.cfg.Ingest.Lenient))
 
-	err = u.storage.SaveData(data)
+	l := u.newLoad(log, index, size, ds.job)
+	defer l.rejects.close(log)
+	places := make(chan *entity.Restaurant, max(u.cfg.Ingest.Buffer, 1))
+	var parseErr error
+	go func() {
+		defer close(places)
+		parseErr = l.validate(ctx, records, places, max(u.cfg.Ingest.ProgressInterval, time.Second))
+		if parseErr != nil {
+			cancel()
+		}
+	}()
+
+	err = u.storage.SaveData(ctx, index, places)
+	if err != nil {
+		cancel()
+	}
+	// SaveData may return before the channel is closed; wait for the validation to end.
+	for range places {
+	}
+	if parseErr != nil {
+		log.Error("failed to parse data: ", sl.Err(parseErr))
+		return parseErr
+	}
 	if err != nil {
 		log.Error("failed to save data: ", sl.Err(err))
+		return err
+	}
+	log.Info("data loaded", append(l.prog.attrs(), slog.Int("rejected", l.rejects.count))...)
+
+	if err := l.checkRejectRate(u.cfg.Ingest.MaxRejectRate); err != nil {
+		log.Error("too many rows rejected", slog.Int("rejected", l.rejects.count), slog.Int("rows", l.prog.rows))
+		return err
+	}
+
+	count, err := u.storage.CountDocuments(ctx, index)
+	if err != nil {
+		log.Error("failed to count documents: ", sl.Err(err))
+		return err
+	}
+	if count != len(l.ids) {
+		log.Error("document count mismatch", slog.Int("expected", len(l.ids)), slog.Int("indexed", count))
+		return fmt.Errorf("indexed %d documents out of %d", count, len(l.ids))
+	}
+	log.Info("successfully saved data", slog.Int("count", count))
+	return nil
+}
+
+// Check reads and validates the configured data set as a load does, without writing to the storage,
+// so that a data set can be tried before it is loaded. Rejected rows are reported as usual,
+// in a report named after the index with a "-dry-run" suffix.
+func (u *UseCase) Check(ctx context.Context) error {
+	const op = "usecase.store.Check"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	if err := u.checkSchema(); err != nil {
+		log.Error("failed to check schema: ", sl.Err(err))
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	records, size, err := u.importer.Import(ctx, u.cfg.DataPath, u.cfg.DataFormat)
+	if err != nil {
+		log.Error("failed to open data: ", sl.Err(err))
+		return err
+	}
+	log.Info("checking data", slog.String("path", u.cfg.DataPath), slog.String("format", u.cfg.DataFormat), slog.Int64("bytes", size),
+		slog.Bool("lenient", u.cfg.Ingest.Lenient))
+
+	l := u.newLoad(log, u.cfg.Index.Name+"-dry-run", size, nil)
+	defer l.rejects.close(log)
+	places := make(chan *entity.Restaurant, max(u.cfg.Ingest.Buffer, 1))
+	go func() {
+		for range places {
+		}
+	}()
+	err = l.validate(ctx, records, places, max(u.cfg.Ingest.ProgressInterval, time.Second))
+	close(places)
+	if err != nil {
+		log.Error("failed to parse data: ", sl.Err(err))
+		return err
+	}
+	if err := l.checkRejectRate(u.cfg.Ingest.MaxRejectRate); err != nil {
+		log.Error("too many rows rejected", slog.Int("rejected", l.rejects.count), slog.Int("rows", l.prog.rows))
+		return err
+	}
+	log.Info("data checked", append(l.prog.attrs(), slog.Int("places", len(l.ids)), slog.Int("rejected", l.rejects.count))...)
+	return nil
+}
+
+// load is the state of a single fill.
+type load struct {
+	log     *slog.Logger
+	lenient bool
+	ids     map[string]struct{}
+	// rejected holds the IDs of the rows rejected for what they hold, rather than for the ID they repeat.
+	rejected map[string]struct{}
+	prog     *progress
+	rejects  *rejects
+	// job is the ingestion job the load is run by, nil for loads of the configured data set.
+	job *job
+}
+
+func (u *UseCase) newLoad(log *slog.Logger, index string, size int64, j *job) *load {
+	j.loading(index, size)
+	return &load{
+		log:      log,
+		lenient:  u.cfg.Ingest.Lenient,
+		ids:      make(map[string]struct{}),
+		rejected: make(map[string]struct{}),
+		prog:     newProgress(size),
+		rejects:  &rejects{open: u.openReport, index: index, job: j},
+		job:      j,
+	}
+}
+
+// validate passes the places of the records on until the records run out, logging the progress
+// every interval. Invalid rows are rejected; in strict mode the first of them ends the load.
+func (l *load) validate(ctx context.Context, records <-chan entity.Record, places chan<- *entity.Restaurant, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case rec, ok := <-records:
+			if !ok {
+				return nil
+			}
+			l.prog.add(rec.Offset)
+			l.job.progress(l.prog, l.rejects.count)
+			if errors.Is(rec.Err, entity.ErrSourceBroken) {
+				return fmt.Errorf("line %d: %w", rec.Line, rec.Err)
+			}
+			if reason := l.check(rec); reason != "" {
+				if err := l.rejects.add(entity.Rejection{Line: rec.Line, Raw: rec.Raw, Reason: reason}); err != nil {
+					return err
+				}
+				l.job.progress(l.prog, l.rejects.count)
+				if rec.Place != nil && rec.Place.ID != "" {
+					if _, ok := l.ids[rec.Place.ID]; !ok {
+						l.rejected[rec.Place.ID] = struct{}{}
+					}
+				}
+				if !l.lenient {
+					return fmt.Errorf("line %d: %s", rec.Line, reason)
+				}
+				continue
+			}
+			l.ids[rec.Place.ID] = struct{}{}
+			select {
+			case places <- rec.Place:
+			case <-ctx.Done():
+				// The storage has given up; its error tells why.
+				return nil
+			}
+		case <-ticker.C:
+			l.log.Info("loading data", append(l.prog.attrs(), slog.Int("rejected", l.rejects.count))...)
+		}
+	}
+}
+
+// checkRejectRate fails a load that rejected a larger share of its rows than allowed.
+func (l *load) checkRejectRate(maxRate float64) error {
+	if rate := float64(l.rejects.count) / float64(max(l.prog.rows, 1)); rate > maxRate {
+		return fmt.Errorf("rejected %d rows out of %d, more than %g%% allowed", l.rejects.count, l.prog.rows, maxRate*100)
 	}
-	log.Info("successfully saved data")
 	return nil
 }
+
+// check tells why the record cannot be loaded, or returns an empty string when it can.
+func (l *load) check(rec entity.Record) string {
+	if rec.Err != nil {
+		return rec.Err.Error()
+	}
+	if err := validatePlace(rec.Place); err != nil {
+		return err.Error()
+	}
+	if _, ok := l.ids[rec.Place.ID]; ok {
+		return fmt.Sprintf("duplicate ID %q", rec.Place.ID)
+	}
+	return ""
+}
+
+// rejects writes the rows a load leaves out to its report, created on the first rejection.
+type rejects struct {
+	open   ReportOpener
+	index  string
+	job    *job
+	report RejectionReport
+	count  int
+}
+
+func (r *rejects) add(rejection entity.Rejection) error {
+	if r.report == nil {
+		report, err := r.open(r.index)
+		if err != nil {
+			return err
+		}
+		r.report = report
+	}
+	r.count++
+	r.job.reject(rejection)
+	return r.report.Add(rejection)
+}
+
+func (r *rejects) close(log *slog.Logger) {
+	if r.report == nil {
+		return
+	}
+	r.job.reported(r.report.Path())
+	if err := r.report.Close(); err != nil {
+		log.Error("failed to write rejection report: ", sl.Err(err))
+		return
+	}
+	log.Info("rejected rows reported", slog.Int("rejected", r.count), slog.String("report", r.report.Path()))
+}
+
+// validatePlace rejects places that would be indexed without an ID or with a location off the globe.
+func validatePlace(p *entity.Restaurant) error {
+	switch {
+	case p.ID == "":
+		return errors.New("place has no ID")
+	case p.Location.Lat < -90 || p.Location.Lat > 90:
+		return fmt.Errorf("latitude %g is out of range", p.Location.Lat)
+	case p.Location.Lon < -180 || p.Location.Lon > 180:
+		return fmt.Errorf("longitude %g is out of range", p.Location.Lon)
+	default:
+		return nil
+	}
+}
+
+// Reindex builds a new generation from the configured data set and makes it current.
+func (u *UseCase) Reindex(ctx context.Context) error {
+	return u.reindex(ctx, u.configured())
+}
+
+func (u *UseCase) reindex(ctx context.Context, ds *dataset) error {
+	index, err := u.CreateIndexWithMapping(ctx)
+	if err != nil {
+		return err
+	}
+	return u.uploadPlaces(ctx, index, ds)
+}
+
+// prune drops the oldest generations beyond the configured number, never touching the current one.
+func (u *UseCase) prune(ctx context.Context) {
+	const op = "usecase.store.prune"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	generations, err := u.storage.Generations(ctx)
+	if err != nil {
+		log.Error("failed to list generations: ", sl.Err(err))
+		return
+	}
+	keep := max(u.cfg.Index.Generations, 1)
+	for i, g := range generations {
+		if i < keep || g.Current {
+			continue
+		}
+		if err := u.storage.DeleteIndex(ctx, g.Name); err != nil {
+			log.Error("failed to drop generation: ", sl.Err(err), slog.String("index", g.Name))
+			continue
+		}
+		log.Info("generation dropped", slog.String("index", g.Name))
+	}
+}
+
+func (u *UseCase) Generations(ctx context.Context) ([]*entity.Generation, error) {
+	const op = "usecase.store.Generations"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	generations, err := u.storage.Generations(ctx)
+	if err != nil {
+		log.Error("failed to list generations: ", sl.Err(err))
+		return nil, usecase.StorageError(err)
+	}
+	return generations, nil
+}
+
+// Rollback makes the named generation current again and returns its name.
+// With an empty name it rolls back to the newest generation older than the current one.
+func (u *UseCase) Rollback(ctx context.Context, to string) (string, error) {
+	const op = "usecase.store.Rollback"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.String("to", to),
+	)
+	generations, err := u.storage.Generations(ctx)
+	if err != nil {
+		log.Error("failed to list generations: ", sl.Err(err))
+		return "", usecase.StorageError(err)
+	}
+
+	target, err := pickGeneration(generations, to)
+	if err != nil {
+		log.Error("no generation to roll back to", sl.Err(err))
+		return "", err
+	}
+	if err = u.storage.SwitchAlias(ctx, target.Name); err != nil {
+		log.Error("failed to switch alias: ", sl.Err(err))
+		return "", usecase.StorageError(err)
+	}
+	log.Info("rolled back", slog.String("index", target.Name))
+	return target.Name, nil
+}
+
+// pickGeneration finds the rollback target in a list of generations ordered newest first.
+func pickGeneration(generations []*entity.Generation, name string) (*entity.Generation, error) {
+	if name != "" {
+		for _, g := range generations {
+			if g.Name == name {
+				return g, nil
+			}
+		}
+		return nil, usecase.ErrGenerationNotFound
+	}
+	seenCurrent := false
+	for _, g := range generations {
+		if seenCurrent {
+			return g, nil
+		}
+		seenCurrent = g.Current
+	}
+	return nil, usecase.ErrGenerationNotFound
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/config"
+	"nearestPlaces/internal/usecase"
+	"reflect"
+	"testing"
+)
+
+// generations lists generations named by the given names, newest first, the one named current being current.
+func generations(current string, names ...string) []*entity.Generation {
+	res := make([]*entity.Generation, 0, len(names))
+	for _, name := range names {
+		res = append(res, &entity.Generation{Name: name, Current: name == current})
+	}
+	return res
+}
+
+func TestPickGeneration(t *testing.T) {
+	tests := []struct {
+		name        string
+		generations []*entity.Generation
+		to          string
+		want        string
+		wantErr     error
+	}{
+		{name: "previous of newest", generations: generations("c", "c", "b", "a"), want: "b"},
+		{name: "previous of middle", generations: generations("b", "c", "b", "a"), want: "a"},
+		{name: "current oldest", generations: generations("a", "c", "b", "a"), wantErr: usecase.ErrGenerationNotFound},
+		{name: "no current", generations: generations("", "c", "b", "a"), wantErr: usecase.ErrGenerationNotFound},
+		{name: "none", generations: nil, wantErr: usecase.ErrGenerationNotFound},
+		{name: "named", generations: generations("c", "c", "b", "a"), to: "a", want: "a"},
+		{name: "named current", generations: generations("c", "c", "b", "a"), to: "c", want: "c"},
+		{name: "named missing", generations: generations("c", "c", "b", "a"), to: "z", wantErr: usecase.ErrGenerationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickGeneration(tt.generations, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("pickGeneration() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.Name != tt.want {
+				t.Errorf("pickGeneration() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+// generationsStub lists fixed generations and records the ones dropped.
+type generationsStub struct {
+	Storage
+	generations []*entity.Generation
+	dropped     []string
+}
+
+func (s *generationsStub) Generations(ctx context.Context) ([]*entity.Generation, error) {
+	return s.generations, nil
+}
+
+func (s *generationsStub) DeleteIndex(ctx context.Context, index string) error {
+	s.dropped = append(s.dropped, index)
+	return nil
+}
+
+func TestUseCase_prune(t *testing.T) {
+	tests := []struct {
+		name        string
+		generations []*entity.Generation
+		keep        int
+		want        []string
+	}{
+		{name: "keep fewer", generations: generations("d", "d", "c", "b", "a"), keep: 2, want: []string{"b", "a"}},
+		{name: "current oldest", generations: generations("a", "d", "c", "b", "a"), keep: 1, want: []string{"c", "b"}},
+		{name: "current beyond keep", generations: generations("b", "d", "c", "b", "a"), keep: 2, want: []string{"a"}},
+		{name: "at least one kept", generations: generations("b", "b", "a"), keep: 0, want: []string{"a"}},
+		{name: "keep all", generations: generations("c", "c", "b", "a"), keep: 3, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			cfg := &config.Config{Index: config.Index{Generations: tt.keep}}
+			storage := &generationsStub{generations: tt.generations}
+			New(log, cfg, schemaStub{}, nil, storage, nil, nil).prune(context.Background())
+			if !reflect.DeepEqual(storage.dropped, tt.want) {
+				t.Errorf("prune() dropped = %v, want %v", storage.dropped, tt.want)
+			}
+		})
+	}
+}
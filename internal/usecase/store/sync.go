@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/lib/logger/sl"
+	"nearestPlaces/internal/usecase"
+	"sort"
+	"time"
+)
+
+// syncSampleSize is how many IDs of each kind of change a sync summary names.
+const syncSampleSize = 10
+
+// Sync brings the current generation in line with the configured data set in place: places are matched
+// by ID and compared by content hash, and only the added, modified and removed ones are sent to the index.
+// The changes are gathered before any of them is sent, so that a data set that fails to load leaves the index
+// as it was. They are then applied to the generation serving queries, though: when applying them fails part way,
+// the generation is left with some of them, and the summary returned along with the error is marked partial.
+// Without a current generation a new one is built from scratch, and dropped if the sync fails.
+func (u *UseCase) Sync(ctx context.Context) (*entity.SyncSummary, error) {
+	return u.syncDataset(ctx, u.configured())
+}
+
+func (u *UseCase) syncDataset(ctx context.Context, ds *dataset) (*entity.SyncSummary, error) {
+	const op = "usecase.store.Sync"
+	log := u.log.With(
+		slog.String("op", op),
+	)
+	index, err := u.currentGeneration(ctx)
+	if err != nil && !errors.Is(err, usecase.ErrGenerationNotFound) {
+		log.Error("failed to find current generation: ", sl.Err(err))
+		return nil, err
+	}
+	fresh := index == ""
+	if fresh {
+		log.Info("no current generation, syncing into a new one")
+		if index, err = u.CreateIndexWithMapping(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	summary, err := u.sync(ctx, index, ds)
+	if err != nil {
+		log.Error("failed to sync index: ", sl.Err(err), slog.String("index", index),
+			slog.Bool("partial", summary != nil && summary.Partial))
+		if fresh {
+			ctx, cancel := cleanupContext(ctx)
+			defer cancel()
+			if err := u.storage.DeleteIndex(ctx, index); err != nil {
+				log.Error("failed to drop unfinished index: ", sl.Err(err))
+			}
+			return nil, err
+		}
+		return summary, err
+	}
+	if fresh {
+		if err = u.storage.SwitchAlias(ctx, index); err != nil {
+			log.Error("failed to switch alias: ", sl.Err(err))
+			return nil, err
+		}
+		u.prune(ctx)
+	}
+	log.Info("index synced",
+		slog.String("index", index),
+		slog.Int("added", summary.Added.Count),
+		slog.Int("modified", summary.Modified.Count),
+		slog.Int("removed", summary.Removed.Count),
+		slog.Int("unchanged", summary.Unchanged.Count),
+		slog.Int("kept", summary.Kept.Count),
+		slog.Int("rejected", summary.Rejected),
+		slog.Any("added_sample", summary.Added.Sample),
+		slog.Any("modified_sample", summary.Modified.Sample),
+		slog.Any("removed_sample", summary.Removed.Sample),
+		slog.Any("kept_sample", summary.Kept.Sample),
+	)
+	return summary, nil
+}
+
+// currentGeneration returns the name of the generation serving queries.
+func (u *UseCase) currentGeneration(ctx context.Context) (string, error) {
+	generations, err := u.storage.Generations(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, g := range generations {
+		if g.Current {
+			return g.Name, nil
+		}
+	}
+	return "", usecase.ErrGenerationNotFound
+}
+
+func (u *UseCase) sync(ctx context.Context, index string, ds *dataset) (*entity.SyncSummary, error) {
+	const op = "usecase.store.sync"
+	log := u.log.With(
+		slog.String("op", op),
+		slog.String("index", index),
+	)
+	stored, err := u.storage.Hashes(ctx, index)
+	if err != nil {
+		log.Error("failed to read hashes: ", sl.Err(err))
+		return nil, err
+	}
+	log.Info("hashes read", slog.Int("count", len(stored)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	records, size, err := u.importer.Import(ctx, ds.path, ds.format)
+	if err != nil {
+		log.Error("failed to open data: ", sl.Err(err))
+		return nil, err
+	}
+	log.Info("syncing data", slog.String("path", ds.path), slog.Int64("bytes", size),
+		slog.Bool("lenient", u.cfg.Ingest.Lenient))
+
+	l := u.newLoad(log, index, size, ds.job)
+	defer l.rejects.close(log)
+	places := make(chan *entity.Restaurant, max(u.cfg.Ingest.Buffer, 1))
+	var parseErr error
+	go func() {
+		defer close(places)
+		parseErr = l.validate(ctx, records, places, max(u.cfg.Ingest.ProgressInterval, time.Second))
+	}()
+
+	summary := &entity.SyncSummary{Index: index}
+	var changes []entity.Change
+	for p := range places {
+		hash, ok := stored[p.ID]
+		switch {
+		case !ok:
+			changes = append(changes, entity.Change{Op: entity.ChangeAdd, ID: p.ID, Place: p})
+			note(&summary.Added, p.ID)
+		case hash != p.ContentHash():
+			changes = append(changes, entity.Change{Op: entity.ChangeModify, ID: p.ID, Place: p})
+			note(&summary.Modified, p.ID)
+		default:
+			note(&summary.Unchanged, p.ID)
+		}
+		delete(stored, p.ID)
+	}
+	if parseErr != nil {
+		log.Error("failed to parse data: ", sl.Err(parseErr))
+		return nil, parseErr
+	}
+	if err := l.checkRejectRate(u.cfg.Ingest.MaxRejectRate); err != nil {
+		log.Error("too many rows rejected", slog.Int("rejected", l.rejects.count), slog.Int("rows", l.prog.rows))
+		return nil, err
+	}
+	summary.Rejected = l.rejects.count
+
+	// A place whose row was rejected keeps the version indexed, rather than being removed for a bad row.
+	kept := make([]string, 0, len(l.rejected))
+	for id := range l.rejected {
+		if _, ok := stored[id]; ok {
+			kept = append(kept, id)
+			delete(stored, id)
+		}
+	}
+	sort.Strings(kept)
+	for _, id := range kept {
+		note(&summary.Kept, id)
+	}
+
+	// What is left in the index has no row in the data set.
+	removed := make([]string, 0, len(stored))
+	for id := range stored {
+		removed = append(removed, id)
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		changes = append(changes, entity.Change{Op: entity.ChangeRemove, ID: id})
+		note(&summary.Removed, id)
+	}
+
+	if len(changes) > 0 {
+		if err := u.storage.ApplyChanges(ctx, index, changes); err != nil {
+			log.Error("failed to apply changes: ", sl.Err(err))
+			// some of the changes may have reached the index before the failure
+			summary.Partial = true
+			return summary, err
+		}
+	}
+	count, err := u.storage.CountDocuments(ctx, index)
+	if err != nil {
+		log.Error("failed to count documents: ", sl.Err(err))
+		return nil, err
+	}
+	if expected := len(l.ids) + len(kept); count != expected {
+		log.Error("document count mismatch", slog.Int("expected", expected), slog.Int("indexed", count))
+		summary.Partial = true
+		return summary, fmt.Errorf("index holds %d documents instead of %d", count, expected)
+	}
+	return summary, nil
+}
+
+// note counts the place in the set, naming it if the sample is not full yet.
+func note(set *entity.ChangeSet, id string) {
+	set.Count++
+	if len(set.Sample) < syncSampleSize {
+		set.Sample = append(set.Sample, id)
+	}
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"nearestPlaces/internal/entity"
+	"nearestPlaces/internal/infrastructure/repository/memory"
+	"nearestPlaces/internal/lib/config"
+	"reflect"
+	"testing"
+)
+
+type schemaStub struct{}
+
+func (schemaStub) ReadMappings(string) ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+// importerStub streams the places it holds, as a data set would.
+type importerStub struct {
+	places []*entity.Restaurant
+}
+
+func (i *importerStub) Import(ctx context.Context, filename, format string) (<-chan entity.Record, int64, error) {
+	out := make(chan entity.Record, len(i.places))
+	for n, p := range i.places {
+		out <- entity.Record{Line: n + 2, Offset: int64(n + 1), Place: p}
+	}
+	close(out)
+	return out, int64(len(i.places)), nil
+}
+
+func (i *importerStub) Format(filename, format string) (string, error) {
+	return format, nil
+}
+
+// reportStub keeps the rejected rows in memory.
+type reportStub struct {
+	rows []entity.Rejection
+}
+
+func (r *reportStub) Add(rejection entity.Rejection) error {
+	r.rows = append(r.rows, rejection)
+	return nil
+}
+
+func (r *reportStub) Path() string {
+	return "report"
+}
+
+func (r *reportStub) Close() error {
+	return nil
+}
+
+func place(id, name string) *entity.Restaurant {
+	p := &entity.Restaurant{ID: id, Name: name}
+	p.Location.Lat, p.Location.Lon = 55.7, 37.6
+	return p
+}
+
+func TestUseCase_Sync(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Ingest: config.Ingest{Buffer: 4, MaxRejectRate: 0.05}}
+	storage := memory.New(log, "places")
+	data := &importerStub{places: []*entity.Restaurant{place("1", "A"), place("2", "B"), place("3", "C")}}
+	openReport := func(string) (RejectionReport, error) {
+		return &reportStub{}, nil
+	}
+	u := New(log, cfg, schemaStub{}, data, storage, openReport, nil)
+
+	first, err := u.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if first.Added.Count != 3 || first.Unchanged.Count != 0 {
+		t.Errorf("Sync() into an empty storage got = %+v, want 3 added", first)
+	}
+
+	data.places = []*entity.Restaurant{place("1", "A"), place("2", "B renamed"), place("4", "D")}
+	second, err := u.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if second.Index != first.Index {
+		t.Errorf("Sync() index = %s, want the current one %s", second.Index, first.Index)
+	}
+	got := map[string][]string{
+		"added":     second.Added.Sample,
+		"modified":  second.Modified.Sample,
+		"removed":   second.Removed.Sample,
+		"unchanged": second.Unchanged.Sample,
+	}
+	want := map[string][]string{
+		"added":     {"4"},
+		"modified":  {"2"},
+		"removed":   {"3"},
+		"unchanged": {"1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sync() changes = %v, want %v", got, want)
+	}
+	places, total, err := storage.GetPlaces(context.Background(), 10, 0)
+	if err != nil || total != 3 {
+		t.Fatalf("GetPlaces() total = %d, error = %v", total, err)
+	}
+	names := make(map[string]string)
+	for _, p := range places {
+		names[p.ID] = p.Name
+	}
+	if !reflect.DeepEqual(names, map[string]string{"1": "A", "2": "B renamed", "4": "D"}) {
+		t.Errorf("GetPlaces() after sync = %v", names)
+	}
+
+	// A place whose row turns invalid keeps its indexed version instead of being removed.
+	cfg.Ingest.Lenient, cfg.Ingest.MaxRejectRate = true, 0.5
+	broken := place("2", "B broken")
+	broken.Location.Lat = 91
+	data.places = []*entity.Restaurant{place("1", "A"), broken, place("4", "D")}
+	third, err := u.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if third.Removed.Count != 0 || third.Rejected != 1 || !reflect.DeepEqual(third.Kept.Sample, []string{"2"}) {
+		t.Errorf("Sync() with a rejected row got = %+v, want place 2 kept", third)
+	}
+	places, total, err = storage.GetPlaces(context.Background(), 10, 0)
+	if err != nil || total != 3 {
+		t.Fatalf("GetPlaces() total = %d, error = %v", total, err)
+	}
+	for _, p := range places {
+		if p.ID == "2" && p.Name != "B renamed" {
+			t.Errorf("GetPlaces() place 2 = %q, want the indexed version", p.Name)
+		}
+	}
+}
+
+// applyFailing fails to apply the changes of a sync after applying the first of them.
+type applyFailing struct {
+	*memory.Storage
+}
+
+func (s applyFailing) ApplyChanges(ctx context.Context, index string, changes []entity.Change) error {
+	if err := s.Storage.ApplyChanges(ctx, index, changes[:1]); err != nil {
+		return err
+	}
+	return errors.New("bulk failed")
+}
+
+func TestUseCase_SyncPartial(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Ingest: config.Ingest{Buffer: 4, MaxRejectRate: 0.05}}
+	storage := memory.New(log, "places")
+	data := &importerStub{places: []*entity.Restaurant{place("1", "A"), place("2", "B")}}
+	if _, err := New(log, cfg, schemaStub{}, data, storage, nil, nil).Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	data.places = []*entity.Restaurant{place("1", "A renamed"), place("2", "B renamed")}
+	summary, err := New(log, cfg, schemaStub{}, data, applyFailing{storage}, nil, nil).Sync(context.Background())
+	if err == nil {
+		t.Fatal("Sync() error = nil, want the bulk failure")
+	}
+	if summary == nil || !summary.Partial || summary.Modified.Count != 2 {
+		t.Errorf("Sync() summary = %+v, want a partial one with 2 modified", summary)
+	}
+}